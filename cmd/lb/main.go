@@ -2,40 +2,104 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"strings"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/eltoncampos/load-balancer/internal/admin"
 	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/internal/clientip"
 	"github.com/eltoncampos/load-balancer/internal/config"
 	"github.com/eltoncampos/load-balancer/internal/handler"
 	"github.com/eltoncampos/load-balancer/internal/healthcheck"
 	"github.com/eltoncampos/load-balancer/internal/pool"
 )
 
+// shutdownTimeout bounds how long in-flight requests may run after a
+// termination signal.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg := config.Load()
 
-	serverPool := pool.New()
-	lb := handler.New(serverPool)
+	resolver, err := clientip.NewResolver(cfg.ClientIPHeader, cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	tokens := strings.SplitSeq(cfg.ServerList, ",")
-	for tok := range tokens {
-		serverURL, err := url.Parse(tok)
-		if err != nil {
-			log.Fatal(err)
-		}
+	hashKey, err := pool.ParseHashKey(cfg.HashKey, resolver.ClientIP)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	balancer, err := pool.NewBalancer(cfg.Strategy, pool.Options{
+		HashKey:    hashKey,
+		Replicas:   cfg.HashReplicas,
+		LoadFactor: cfg.HashLoadFactor,
+		ClientIP:   resolver.ClientIP,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	events := healthcheck.NewBus()
+	poolOpts := []pool.Option{
+		pool.WithBalancer(balancer),
+		pool.WithZone(cfg.Zone, cfg.SpilloverThreshold),
+		pool.WithWatcher(events.Watch),
+	}
+	if cfg.OutlierDetection {
+		poolOpts = append(poolOpts, pool.WithOutlierDetection(cfg.Outlier))
+	}
+	serverPool := pool.New(poolOpts...)
+	lbOpts := []handler.Option{
+		handler.WithClientIPResolver(resolver),
+		handler.WithRetryRoutes(cfg.RetryRoutes...),
+		handler.WithRetryBudget(handler.NewRetryBudget(cfg.RetryBudgetRatio, cfg.RetryBudgetMin, cfg.RetryBudgetWindow)),
+		handler.WithMaxRetryBody(cfg.RetryMaxBody),
+	}
+	if cfg.StickySessions {
+		lbOpts = append(lbOpts, handler.WithStickySessions(stickySecret(cfg.StickySecret)))
+	}
+	lb := handler.New(serverPool, lbOpts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.HealthWebhook != "" {
+		webhook := healthcheck.NewWebhook(cfg.HealthWebhook, healthcheck.WithRetries(cfg.HealthWebhookRetries))
+		events.Subscribe(webhook.Notify)
+		go webhook.Run(ctx)
+	}
+
+	for _, spec := range cfg.Backends {
+		serverURL := spec.URL
 
 		proxy := httputil.NewSingleHostReverseProxy(serverURL)
 		proxy.ErrorHandler = createProxyErrorHandler(lb, serverPool, serverURL)
 
 		b := backend.New(serverURL, proxy)
+		b.Weight = spec.Weight
+		b.Priority = spec.Priority
+		b.Zone = spec.Zone
+		b.Prober = spec.Prober
+		b.HealthAddr = spec.HealthAddr
+		b.Rise = spec.Rise
+		b.Fall = spec.Fall
+		b.SlowStart = time.Duration(cfg.SlowStart) * time.Second
+		b.Breaker = cfg.Breaker
 		serverPool.AddBackend(b)
-		log.Printf("Configured server: %s\n", serverURL)
+		log.Printf("Configured server: %s (weight %d, priority %d)\n", serverURL, spec.Weight, spec.Priority)
 	}
 
 	server := http.Server{
@@ -43,21 +107,88 @@ func main() {
 		Handler: lb,
 	}
 
-	go healthcheck.StartHealthCheck(
-		serverPool.GetBackends(),
+	checker := healthcheck.NewChecker(
+		serverPool.GetBackends,
 		time.Duration(cfg.HealthCheckInterval)*time.Second,
+		healthcheck.WithWorkers(cfg.HealthCheckWorkers),
+		healthcheck.WithJitter(cfg.HealthCheckJitter),
 	)
+	go checker.Run(ctx)
 
-	log.Printf("Load Balancer started on port %d\n", cfg.Port)
-	if err := server.ListenAndServe(); err != nil {
+	if cfg.AdminPort > 0 {
+		adminServer := http.Server{
+			Addr:    net.JoinHostPort(cfg.AdminAddr, strconv.Itoa(cfg.AdminPort)),
+			Handler: admin.New(serverPool, time.Duration(cfg.DrainTimeout)*time.Second),
+		}
+		go func() {
+			log.Printf("Admin API started on %s\n", adminServer.Addr)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+		defer adminServer.Close()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		log.Println("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown: %s\n", err)
+		}
+	}()
+
+	log.Printf("Load Balancer started on port %d using %s\n", cfg.Port, cfg.Strategy)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-stopped
+	log.Println("Load Balancer stopped")
+}
+
+func stickySecret(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	log.Println("No -sticky-secret given, generating one; sessions will not survive restarts or span instances")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
 		log.Fatal(err)
 	}
+	return key
 }
 
 func createProxyErrorHandler(lb *handler.LoadBalancer, serverPool *pool.ServerPool, serverURL *url.URL) func(http.ResponseWriter, *http.Request, error) {
 	return func(w http.ResponseWriter, req *http.Request, e error) {
 		log.Printf("[%s] %s\n", serverURL.Host, e.Error())
 
+		if errors.Is(e, backend.ErrCircuitOpen) {
+			// The breaker tripped after the backend was picked; it is not
+			// down, so move on to another one straight away.
+			attempts := handler.GetAttemptsFromContext(req)
+			ctx := context.WithValue(req.Context(), handler.Attempts, attempts+1)
+			lb.ServeHTTP(w, req.WithContext(ctx))
+			return
+		}
+
+		markDown := func(reason string) {
+			if b := serverPool.GetBackend(serverURL); b != nil {
+				b.MarkDown(reason + ": " + e.Error())
+			}
+		}
+
+		if !lb.AllowRetry(req) {
+			// The backend failed all the same, so it leaves the rotation
+			// even though this request cannot move on to another one.
+			markDown("proxy request failed without retry")
+			log.Printf("%s(%s) Not retrying %s request\n", handler.GetClientIPFromContext(req), req.URL.Path, req.Method)
+			handler.Error(w, "Bad Gateway", http.StatusBadGateway)
+			return
+		}
+
 		retries := handler.GetRetryFromContext(req)
 		if retries < 3 {
 			time.Sleep(10 * time.Millisecond)
@@ -67,10 +198,10 @@ func createProxyErrorHandler(lb *handler.LoadBalancer, serverPool *pool.ServerPo
 			return
 		}
 
-		serverPool.MarkBackendStatus(serverURL, false)
+		markDown("proxy retries exhausted")
 
 		attempts := handler.GetAttemptsFromContext(req)
-		log.Printf("%s(%s) Attempting retry %d\n", req.RemoteAddr, req.URL.Path, attempts)
+		log.Printf("%s(%s) Attempting retry %d\n", handler.GetClientIPFromContext(req), req.URL.Path, attempts)
 		ctx := context.WithValue(req.Context(), handler.Attempts, attempts+1)
 		lb.ServeHTTP(w, req.WithContext(ctx))
 	}
@@ -3,14 +3,19 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
 	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/eltoncampos/load-balancer/internal/backend"
 	"github.com/eltoncampos/load-balancer/internal/handler"
+	"github.com/eltoncampos/load-balancer/internal/healthcheck"
 	"github.com/eltoncampos/load-balancer/internal/pool"
 	"github.com/eltoncampos/load-balancer/testutil"
 )
@@ -284,3 +289,162 @@ func TestCreateProxyErrorHandler_NoRetryHeader(t *testing.T) {
 		t.Errorf("expected X-Retry-Count header to be '1' for first retry, got '%s'", retryHeader)
 	}
 }
+
+func TestCreateProxyErrorHandler_CircuitOpenDoesNotMarkDown(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	b := createTestBackend(server.URL)
+	serverPool := createTestPool(b)
+	lb := handler.New(serverPool)
+
+	errorHandler := createProxyErrorHandler(lb, serverPool, serverURL)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	ctx := context.WithValue(req.Context(), handler.Retry, 3)
+	req = req.WithContext(ctx)
+
+	w := httptest.NewRecorder()
+
+	errorHandler(w, req, backend.ErrCircuitOpen)
+
+	if !b.IsAlive() {
+		t.Error("expected an open circuit not to mark the backend down")
+	}
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected request to be served by another attempt, got %d", w.Code)
+	}
+}
+
+func TestCreateProxyErrorHandler_PublishesDownEvent(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	b := createTestBackend(server.URL)
+	bus := healthcheck.NewBus()
+	var events []healthcheck.Event
+	bus.Subscribe(func(e healthcheck.Event) { events = append(events, e) })
+	serverPool := pool.New(pool.WithWatcher(bus.Watch))
+	serverPool.AddBackend(b)
+	lb := handler.New(serverPool)
+
+	errorHandler := createProxyErrorHandler(lb, serverPool, serverURL)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	ctx := context.WithValue(req.Context(), handler.Retry, 3)
+	req = req.WithContext(ctx)
+
+	errorHandler(httptest.NewRecorder(), req, errors.New("connection reset"))
+
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+
+	if events[0].Backend != b || events[0].NewState != healthcheck.StateDown ||
+		events[0].Reason != "proxy retries exhausted: connection reset" {
+		t.Errorf("expected down event for the backend, got %+v", events[0])
+	}
+}
+
+func TestCreateProxyErrorHandler_DoesNotRetryNonIdempotent(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	b := createTestBackend(server.URL)
+	serverPool := createTestPool(b)
+	lb := handler.New(serverPool)
+
+	errorHandler := createProxyErrorHandler(lb, serverPool, serverURL)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/orders", nil)
+	errorHandler(w, req, errors.New("test error"))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+	}
+
+	if req.Header.Get("X-Retry-Count") != "" {
+		t.Error("expected no retry to be attempted")
+	}
+
+	if n := hits.Load(); n != 0 {
+		t.Errorf("expected the request not to be resent, backend got %d", n)
+	}
+}
+
+func TestCreateProxyErrorHandler_MarksDownWithoutRetry(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		opts   []handler.Option
+	}{
+		{name: "retry budget spent", method: http.MethodGet, opts: []handler.Option{handler.WithRetryBudget(handler.NewRetryBudget(0, 0, time.Minute))}},
+		{name: "non-idempotent request", method: http.MethodPost},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.NotFoundHandler())
+			server.Close()
+
+			failing := createTestBackend(server.URL)
+			serverPool := createTestPool(failing)
+			lb := handler.New(serverPool, tc.opts...)
+			failing.ReverseProxy.ErrorHandler = createProxyErrorHandler(lb, serverPool, failing.URL)
+
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, httptest.NewRequest(tc.method, "/orders", nil))
+
+			if w.Code != http.StatusBadGateway {
+				t.Errorf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+			}
+
+			if failing.IsAlive() {
+				t.Error("expected the failing backend to be marked down without a retry")
+			}
+		})
+	}
+}
+
+func TestCreateProxyErrorHandler_RetryResendsBody(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		conn, _, _ := http.NewResponseController(w).Hijack()
+		conn.Close()
+	}))
+	defer failing.Close()
+
+	received := make(chan string, 1)
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer healthy.Close()
+
+	failingBackend := createTestBackend(failing.URL)
+	healthyBackend := createTestBackend(healthy.URL)
+	serverPool := createTestPool(healthyBackend, failingBackend)
+	lb := handler.New(serverPool)
+	for _, b := range []*backend.Backend{failingBackend, healthyBackend} {
+		b.ReverseProxy.ErrorHandler = createProxyErrorHandler(lb, serverPool, b.URL)
+	}
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("PUT", "/orders/1", strings.NewReader(`{"qty": 3}`)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if got := <-received; got != `{"qty": 3}` {
+		t.Errorf("expected the retried body to arrive intact, got %q", got)
+	}
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/internal/pool"
+)
+
+type Admin struct {
+	pool         *pool.ServerPool
+	drainTimeout time.Duration
+	mux          *http.ServeMux
+}
+
+func New(p *pool.ServerPool, drainTimeout time.Duration) *Admin {
+	a := &Admin{
+		pool:         p,
+		drainTimeout: drainTimeout,
+		mux:          http.NewServeMux(),
+	}
+	a.mux.HandleFunc("GET /backends", a.listBackends)
+	a.mux.HandleFunc("POST /backends/drain", a.drainBackend)
+	return a
+}
+
+func (a *Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
+}
+
+func (a *Admin) listBackends(w http.ResponseWriter, r *http.Request) {
+	backends := a.pool.GetBackends()
+	stats := make([]backend.Stats, 0, len(backends))
+	for _, b := range backends {
+		stats = append(stats, b.Stats())
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *Admin) drainBackend(w http.ResponseWriter, r *http.Request) {
+	u, ok := backendURL(w, r)
+	if !ok {
+		return
+	}
+
+	timeout := a.drainTimeout
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+
+	b := a.pool.GetBackend(u)
+	if b == nil {
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Admin: draining %s (timeout %s)\n", u, timeout)
+	if _, ok := a.pool.Drain(u, timeout); !ok {
+		// Removed since the lookup above.
+		http.Error(w, "backend not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, b.Stats())
+}
+
+func backendURL(w http.ResponseWriter, r *http.Request) (*url.URL, bool) {
+	raw := r.URL.Query().Get("url")
+	if raw == "" {
+		http.Error(w, "missing url parameter", http.StatusBadRequest)
+		return nil, false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		http.Error(w, "invalid url parameter", http.StatusBadRequest)
+		return nil, false
+	}
+	return u, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Admin: encoding response:", err)
+	}
+}
@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/internal/pool"
+)
+
+func createTestBackend(urlStr string) *backend.Backend {
+	u, _ := url.Parse(urlStr)
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	return backend.New(u, proxy)
+}
+
+func createTestPool(backends ...*backend.Backend) *pool.ServerPool {
+	p := pool.New()
+	for _, b := range backends {
+		p.AddBackend(b)
+	}
+	return p
+}
+
+func TestListBackends(t *testing.T) {
+	b1 := createTestBackend("http://localhost:8080")
+	b2 := createTestBackend("http://localhost:8081")
+	b2.SetAlive(false)
+	b1.IncActive()
+	a := New(createTestPool(b1, b2), time.Second)
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", "/backends", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var stats []backend.Stats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 backends, got %d", len(stats))
+	}
+
+	if stats[0].URL != "http://localhost:8080" || !stats[0].Alive || stats[0].ActiveRequests != 1 {
+		t.Errorf("unexpected first backend %+v", stats[0])
+	}
+
+	if stats[1].Alive {
+		t.Error("expected second backend to be reported down")
+	}
+}
+
+func TestDrainBackend(t *testing.T) {
+	b := createTestBackend("http://localhost:8080")
+	b.IncActive()
+	p := createTestPool(b)
+	a := New(p, time.Minute)
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("POST", "/backends/drain?url=http://localhost:8080", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+
+	var stats backend.Stats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if !stats.Draining || stats.ActiveRequests != 1 {
+		t.Errorf("expected draining backend with 1 in-flight request, got %+v", stats)
+	}
+
+	if !b.IsDraining() {
+		t.Error("expected backend to be draining")
+	}
+}
+
+func TestDrainBackend_WithTimeout(t *testing.T) {
+	b := createTestBackend("http://localhost:8080")
+	b.IncActive()
+	p := createTestPool(b)
+	a := New(p, time.Hour)
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("POST", "/backends/drain?url=http://localhost:8080&timeout=10ms", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(p.GetBackends()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(p.GetBackends()) != 0 {
+		t.Error("expected backend to be removed after the requested timeout")
+	}
+}
+
+func TestDrainBackend_Errors(t *testing.T) {
+	a := New(createTestPool(createTestBackend("http://localhost:8080")), time.Second)
+
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{name: "missing url", method: "POST", target: "/backends/drain", status: http.StatusBadRequest},
+		{name: "unknown backend", method: "POST", target: "/backends/drain?url=http://localhost:9999", status: http.StatusNotFound},
+		{name: "invalid timeout", method: "POST", target: "/backends/drain?url=http://localhost:8080&timeout=soon", status: http.StatusBadRequest},
+		{name: "wrong method", method: "GET", target: "/backends/drain?url=http://localhost:8080", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+
+			if w.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
@@ -1,30 +1,240 @@
 package backend
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Prober checks whether a backend can serve traffic. Implementations live in
+// the healthcheck package; a nil Prober means the default TCP check.
+type Prober interface {
+	Probe(ctx context.Context, b *Backend) error
+}
+
 type Backend struct {
 	URL          *url.URL
 	Alive        bool
+	Weight       int
+	Priority     int
+	Zone         string
+	Prober       Prober
+	HealthAddr   string
+	Rise         int
+	Fall         int
+	SlowStart    time.Duration
+	Breaker      BreakerConfig
 	mux          sync.RWMutex
 	ReverseProxy *httputil.ReverseProxy
+	active       atomic.Int64
+	latency      ewma
+	draining     bool
+	ejected      bool
+	circuit      circuit
+	upSince      time.Time
+	reason       string
+	successes    int
+	failures     int
+	listeners    map[int]func(*Backend)
+	responses    map[int]func(*Backend, int, error)
+	nextID       int
+}
+
+// slowStartMinFactor is the share of its weight a backend starts with when
+// slow start is enabled.
+const slowStartMinFactor = 0.1
+
+type Stats struct {
+	URL             string        `json:"url"`
+	Alive           bool          `json:"alive"`
+	Draining        bool          `json:"draining"`
+	Ejected         bool          `json:"ejected"`
+	Circuit         string        `json:"circuit,omitempty"`
+	Weight          int           `json:"weight"`
+	EffectiveWeight float64       `json:"effective_weight"`
+	Priority        int           `json:"priority"`
+	Zone            string        `json:"zone,omitempty"`
+	ActiveRequests  int64         `json:"active_requests"`
+	Latency         time.Duration `json:"latency_ewma"`
 }
 
 func New(url *url.URL, proxy *httputil.ReverseProxy) *Backend {
-	return &Backend{
+	b := &Backend{
 		URL:          url,
 		Alive:        true,
+		Weight:       1,
 		ReverseProxy: proxy,
+		upSince:      time.Now(),
 	}
+	if proxy != nil {
+		next := proxy.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		proxy.Transport = &transport{backend: b, next: next}
+	}
+	return b
+}
+
+// ID is a stable, opaque identifier derived from the backend URL, safe to
+// hand out to clients.
+func (b *Backend) ID() string {
+	sum := sha256.Sum256([]byte(b.URL.String()))
+	return hex.EncodeToString(sum[:8])
 }
 
 func (b *Backend) SetAlive(alive bool) {
+	b.setAlive(alive, "")
+}
+
+// MarkDown takes the backend out of rotation, recording why for state change
+// listeners.
+func (b *Backend) MarkDown(reason string) {
+	b.setAlive(false, reason)
+}
+
+func (b *Backend) setAlive(alive bool, reason string) {
+	b.setState(func() bool {
+		changed := b.Alive != alive
+		if changed {
+			b.reason = reason
+			if alive {
+				b.upSince = time.Now()
+			}
+		}
+		b.Alive = alive
+		b.successes, b.failures = 0, 0
+		return changed
+	})
+}
+
+// RecordProbe feeds a health check result, nil on success, into the
+// rise/fall counters. A dead backend comes back after Rise consecutive
+// successes and an alive one goes down after Fall consecutive failures;
+// thresholds below 1 count as 1. It reports whether the alive state changed.
+func (b *Backend) RecordProbe(err error) bool {
+	var changed bool
+	b.setState(func() bool {
+		if err == nil {
+			b.successes++
+			b.failures = 0
+			if !b.Alive && b.successes >= max(b.Rise, 1) {
+				b.Alive = true
+				b.upSince = time.Now()
+				b.reason = "health check passed"
+				changed = true
+			}
+		} else {
+			b.failures++
+			b.successes = 0
+			if b.Alive && b.failures >= max(b.Fall, 1) {
+				b.Alive = false
+				b.reason = "health check failed: " + err.Error()
+				changed = true
+			}
+		}
+		return changed
+	})
+	return changed
+}
+
+// SetDraining stops (or resumes) new requests being routed to the backend
+// while letting in-flight ones finish.
+func (b *Backend) SetDraining(draining bool) {
+	b.setState(func() bool {
+		changed := b.draining != draining
+		b.draining = draining
+		return changed
+	})
+}
+
+// SetEjected takes the backend out of rotation (or puts it back) after outlier
+// detection has seen it fail real traffic.
+func (b *Backend) SetEjected(ejected bool) {
+	b.setState(func() bool {
+		changed := b.ejected != ejected
+		b.ejected = ejected
+		return changed
+	})
+}
+
+// setState applies fn under the lock and notifies listeners if it reports a
+// change.
+func (b *Backend) setState(fn func() bool) {
+	b.mux.Lock()
+	changed := fn()
+	listeners := make([]func(*Backend), 0, len(b.listeners))
+	for _, fn := range b.listeners {
+		listeners = append(listeners, fn)
+	}
+	b.mux.Unlock()
+
+	if changed {
+		for _, fn := range listeners {
+			fn(b)
+		}
+	}
+}
+
+// OnStateChange registers fn to be called after the backend's alive,
+// draining, ejected or circuit state changes. The returned function unregisters it.
+func (b *Backend) OnStateChange(fn func(*Backend)) func() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.listeners == nil {
+		b.listeners = make(map[int]func(*Backend))
+	}
+	id := b.nextID
+	b.nextID++
+	b.listeners[id] = fn
+
+	return func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		delete(b.listeners, id)
+	}
+}
+
+// OnResponse registers fn to be called with the status code, or the error,
+// of every proxied round trip. The returned function unregisters it.
+func (b *Backend) OnResponse(fn func(b *Backend, status int, err error)) func() {
 	b.mux.Lock()
 	defer b.mux.Unlock()
-	b.Alive = alive
+	if b.responses == nil {
+		b.responses = make(map[int]func(*Backend, int, error))
+	}
+	id := b.nextID
+	b.nextID++
+	b.responses[id] = fn
+
+	return func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		delete(b.responses, id)
+	}
+}
+
+func (b *Backend) observeResponse(status int, err error) {
+	b.mux.RLock()
+	if len(b.responses) == 0 {
+		b.mux.RUnlock()
+		return
+	}
+	listeners := make([]func(*Backend, int, error), 0, len(b.responses))
+	for _, fn := range b.responses {
+		listeners = append(listeners, fn)
+	}
+	b.mux.RUnlock()
+
+	for _, fn := range listeners {
+		fn(b, status, err)
+	}
 }
 
 func (b *Backend) IsAlive() bool {
@@ -32,3 +242,89 @@ func (b *Backend) IsAlive() bool {
 	defer b.mux.RUnlock()
 	return b.Alive
 }
+
+// AliveReason reports whether the backend is alive together with why it last
+// went up or down; the reason is empty when it was set through SetAlive.
+func (b *Backend) AliveReason() (bool, string) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.Alive, b.reason
+}
+
+func (b *Backend) IsDraining() bool {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.draining
+}
+
+func (b *Backend) IsEjected() bool {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.ejected
+}
+
+// IsAvailable reports whether the backend may receive new requests.
+func (b *Backend) IsAvailable() bool {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.Alive && !b.draining && !b.ejected && b.circuitAvailable()
+}
+
+// EffectiveWeight is the weight strategies should balance on. During the
+// slow-start window after the backend comes up it ramps linearly from a
+// small fraction of Weight to the full value.
+func (b *Backend) EffectiveWeight() float64 {
+	weight := float64(b.Weight)
+	if b.SlowStart <= 0 {
+		return weight
+	}
+
+	b.mux.RLock()
+	elapsed := time.Since(b.upSince)
+	b.mux.RUnlock()
+
+	if elapsed >= b.SlowStart {
+		return weight
+	}
+	return weight * max(slowStartMinFactor, float64(elapsed)/float64(b.SlowStart))
+}
+
+func (b *Backend) IncActive() {
+	b.active.Add(1)
+}
+
+func (b *Backend) DecActive() {
+	b.active.Add(-1)
+}
+
+func (b *Backend) ActiveRequests() int64 {
+	return b.active.Load()
+}
+
+func (b *Backend) ObserveLatency(d time.Duration) {
+	b.latency.observe(float64(d))
+}
+
+func (b *Backend) LatencyEWMA() time.Duration {
+	return time.Duration(b.latency.value())
+}
+
+func (b *Backend) Stats() Stats {
+	var circuit string
+	if b.Breaker.FailureThreshold > 0 {
+		circuit = b.CircuitState().String()
+	}
+	return Stats{
+		URL:             b.URL.String(),
+		Alive:           b.IsAlive(),
+		Draining:        b.IsDraining(),
+		Ejected:         b.IsEjected(),
+		Circuit:         circuit,
+		Weight:          b.Weight,
+		EffectiveWeight: b.EffectiveWeight(),
+		Priority:        b.Priority,
+		Zone:            b.Zone,
+		ActiveRequests:  b.ActiveRequests(),
+		Latency:         b.LatencyEWMA(),
+	}
+}
@@ -1,10 +1,12 @@
 package backend
 
 import (
+	"errors"
 	"net/http/httputil"
 	"net/url"
 	"sync"
 	"testing"
+	"time"
 )
 
 func createTestBackend() *Backend {
@@ -28,6 +30,10 @@ func TestNew(t *testing.T) {
 		t.Error("expected new backend to be alive")
 	}
 
+	if b.Weight != 1 {
+		t.Errorf("expected default weight 1, got %d", b.Weight)
+	}
+
 	if b.ReverseProxy != proxy {
 		t.Error("expected reverse proxy to match")
 	}
@@ -109,3 +115,360 @@ func TestSetAliveThreadSafety(t *testing.T) {
 		t.Error("expected alive to be either true or false")
 	}
 }
+
+func TestActiveRequests(t *testing.T) {
+	b := createTestBackend()
+
+	if b.ActiveRequests() != 0 {
+		t.Errorf("expected 0 active requests, got %d", b.ActiveRequests())
+	}
+
+	b.IncActive()
+	b.IncActive()
+	if b.ActiveRequests() != 2 {
+		t.Errorf("expected 2 active requests, got %d", b.ActiveRequests())
+	}
+
+	b.DecActive()
+	if b.ActiveRequests() != 1 {
+		t.Errorf("expected 1 active request, got %d", b.ActiveRequests())
+	}
+}
+
+func TestActiveRequests_Concurrent(t *testing.T) {
+	b := createTestBackend()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.IncActive()
+			b.DecActive()
+		}()
+	}
+
+	wg.Wait()
+
+	if b.ActiveRequests() != 0 {
+		t.Errorf("expected 0 active requests, got %d", b.ActiveRequests())
+	}
+}
+
+func TestStats(t *testing.T) {
+	b := createTestBackend()
+	b.Weight = 3
+	b.IncActive()
+	b.ObserveLatency(5 * time.Millisecond)
+
+	s := b.Stats()
+
+	if s.URL != "http://localhost:8080" || !s.Alive || s.Weight != 3 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	if s.ActiveRequests != 1 {
+		t.Errorf("expected 1 active request, got %d", s.ActiveRequests)
+	}
+
+	if s.Latency != 5*time.Millisecond {
+		t.Errorf("expected 5ms latency, got %s", s.Latency)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	b := createTestBackend()
+
+	var calls []bool
+	b.OnStateChange(func(changed *Backend) {
+		if changed != b {
+			t.Error("expected listener to receive the backend")
+		}
+		calls = append(calls, changed.IsAlive())
+	})
+
+	b.SetAlive(true)
+	b.SetAlive(false)
+	b.SetAlive(false)
+	b.SetAlive(true)
+
+	if len(calls) != 2 || calls[0] != false || calls[1] != true {
+		t.Errorf("expected listener to fire only on transitions, got %v", calls)
+	}
+}
+
+func TestID(t *testing.T) {
+	b1 := createTestBackend()
+	b2 := createTestBackend()
+	u, _ := url.Parse("http://localhost:8081")
+	other := New(u, nil)
+
+	if b1.ID() != b2.ID() {
+		t.Error("expected backends with the same URL to share an ID")
+	}
+
+	if b1.ID() == other.ID() {
+		t.Error("expected backends with different URLs to have different IDs")
+	}
+
+	if len(b1.ID()) != 16 {
+		t.Errorf("expected 16 character ID, got %q", b1.ID())
+	}
+}
+
+func TestOnStateChange_Unregister(t *testing.T) {
+	b := createTestBackend()
+
+	calls := 0
+	cancel := b.OnStateChange(func(*Backend) { calls++ })
+
+	b.SetAlive(false)
+	cancel()
+	b.SetAlive(true)
+
+	if calls != 1 {
+		t.Errorf("expected 1 call before unregistering, got %d", calls)
+	}
+}
+
+func TestSetDraining(t *testing.T) {
+	b := createTestBackend()
+
+	if b.IsDraining() || !b.IsAvailable() {
+		t.Fatal("expected new backend to be available and not draining")
+	}
+
+	b.SetDraining(true)
+	if !b.IsDraining() {
+		t.Error("expected backend to be draining")
+	}
+	if b.IsAvailable() {
+		t.Error("expected draining backend to be unavailable")
+	}
+	if !b.IsAlive() {
+		t.Error("expected draining not to affect liveness")
+	}
+
+	b.SetDraining(false)
+	if !b.IsAvailable() {
+		t.Error("expected backend to be available again")
+	}
+}
+
+func TestIsAvailable_Dead(t *testing.T) {
+	b := createTestBackend()
+	b.SetAlive(false)
+
+	if b.IsAvailable() {
+		t.Error("expected dead backend to be unavailable")
+	}
+}
+
+func TestOnStateChange_Draining(t *testing.T) {
+	b := createTestBackend()
+
+	calls := 0
+	b.OnStateChange(func(*Backend) { calls++ })
+
+	b.SetDraining(true)
+	b.SetDraining(true)
+	b.SetDraining(false)
+
+	if calls != 2 {
+		t.Errorf("expected 2 notifications, got %d", calls)
+	}
+}
+
+func TestEffectiveWeight_WithoutSlowStart(t *testing.T) {
+	b := createTestBackend()
+	b.Weight = 4
+
+	if b.EffectiveWeight() != 4 {
+		t.Errorf("expected full weight 4, got %v", b.EffectiveWeight())
+	}
+}
+
+func TestEffectiveWeight_RampsAfterRecovery(t *testing.T) {
+	b := createTestBackend()
+	b.Weight = 10
+	b.SlowStart = time.Hour
+
+	b.SetAlive(false)
+	b.SetAlive(true)
+
+	if w := b.EffectiveWeight(); w != 1 {
+		t.Errorf("expected weight to start at the minimum fraction, got %v", w)
+	}
+
+	b.mux.Lock()
+	b.upSince = time.Now().Add(-30 * time.Minute)
+	b.mux.Unlock()
+
+	if w := b.EffectiveWeight(); w < 4.9 || w > 5.1 {
+		t.Errorf("expected half weight half way through the window, got %v", w)
+	}
+
+	b.mux.Lock()
+	b.upSince = time.Now().Add(-2 * time.Hour)
+	b.mux.Unlock()
+
+	if w := b.EffectiveWeight(); w != 10 {
+		t.Errorf("expected full weight after the window, got %v", w)
+	}
+}
+
+func TestEffectiveWeight_StaysUpDoesNotReset(t *testing.T) {
+	b := createTestBackend()
+	b.SlowStart = time.Hour
+	b.mux.Lock()
+	b.upSince = time.Now().Add(-2 * time.Hour)
+	b.mux.Unlock()
+
+	b.SetAlive(true)
+
+	if b.EffectiveWeight() != 1 {
+		t.Error("expected SetAlive on an alive backend not to restart slow start")
+	}
+}
+
+var errProbe = errors.New("connection refused")
+
+func TestAliveReason(t *testing.T) {
+	b := createTestBackend()
+
+	b.RecordProbe(errProbe)
+	if alive, reason := b.AliveReason(); alive || reason != "health check failed: connection refused" {
+		t.Errorf("expected probe failure reason, got %v %q", alive, reason)
+	}
+
+	b.RecordProbe(nil)
+	if alive, reason := b.AliveReason(); !alive || reason != "health check passed" {
+		t.Errorf("expected probe success reason, got %v %q", alive, reason)
+	}
+
+	b.MarkDown("proxy retries exhausted")
+	if alive, reason := b.AliveReason(); alive || reason != "proxy retries exhausted" {
+		t.Errorf("expected mark down reason, got %v %q", alive, reason)
+	}
+
+	b.SetAlive(true)
+	if _, reason := b.AliveReason(); reason != "" {
+		t.Errorf("expected no reason after SetAlive, got %q", reason)
+	}
+}
+
+func TestRecordProbe_DefaultThresholds(t *testing.T) {
+	b := createTestBackend()
+
+	if !b.RecordProbe(errProbe) || b.IsAlive() {
+		t.Error("expected a single failure to take the backend down by default")
+	}
+
+	if !b.RecordProbe(nil) || !b.IsAlive() {
+		t.Error("expected a single success to bring the backend up by default")
+	}
+
+	if b.RecordProbe(nil) {
+		t.Error("expected no change when an alive backend passes again")
+	}
+}
+
+func TestRecordProbe_Fall(t *testing.T) {
+	b := createTestBackend()
+	b.Fall = 3
+
+	b.RecordProbe(errProbe)
+	b.RecordProbe(errProbe)
+	if !b.IsAlive() {
+		t.Fatal("expected backend to stay up before reaching the fall threshold")
+	}
+
+	b.RecordProbe(nil)
+	b.RecordProbe(errProbe)
+	b.RecordProbe(errProbe)
+	if !b.IsAlive() {
+		t.Fatal("expected a success to reset the failure count")
+	}
+
+	if !b.RecordProbe(errProbe) || b.IsAlive() {
+		t.Error("expected backend to go down after 3 consecutive failures")
+	}
+}
+
+func TestRecordProbe_Rise(t *testing.T) {
+	b := createTestBackend()
+	b.Rise = 2
+	b.SetAlive(false)
+
+	b.RecordProbe(nil)
+	if b.IsAlive() {
+		t.Fatal("expected backend to stay down before reaching the rise threshold")
+	}
+
+	b.RecordProbe(errProbe)
+	b.RecordProbe(nil)
+	if b.IsAlive() {
+		t.Fatal("expected a failure to reset the success count")
+	}
+
+	if !b.RecordProbe(nil) || !b.IsAlive() {
+		t.Error("expected backend to come up after 2 consecutive successes")
+	}
+}
+
+func TestRecordProbe_SetAliveResetsCounters(t *testing.T) {
+	b := createTestBackend()
+	b.Rise = 2
+	b.RecordProbe(nil)
+
+	b.SetAlive(false)
+	b.RecordProbe(nil)
+
+	if b.IsAlive() {
+		t.Error("expected successes before SetAlive not to count towards rise")
+	}
+}
+
+func TestRecordProbe_NotifiesListeners(t *testing.T) {
+	b := createTestBackend()
+	b.Fall = 2
+
+	calls := 0
+	b.OnStateChange(func(*Backend) { calls++ })
+
+	b.RecordProbe(errProbe)
+	b.RecordProbe(errProbe)
+	b.RecordProbe(errProbe)
+
+	if calls != 1 {
+		t.Errorf("expected exactly one notification, got %d", calls)
+	}
+}
+
+func TestSetEjected(t *testing.T) {
+	b := createTestBackend()
+
+	calls := 0
+	b.OnStateChange(func(*Backend) { calls++ })
+
+	b.SetEjected(true)
+
+	if !b.IsEjected() || b.IsAvailable() {
+		t.Error("expected ejected backend to be unavailable")
+	}
+
+	if !b.Stats().Ejected {
+		t.Error("expected stats to report the ejection")
+	}
+
+	b.SetEjected(true)
+	b.SetEjected(false)
+
+	if !b.IsAvailable() {
+		t.Error("expected backend to be available after the ejection ends")
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 notifications, got %d", calls)
+	}
+}
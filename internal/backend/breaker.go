@@ -0,0 +1,147 @@
+package backend
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the backend's transport when its circuit
+// breaker rejects a request.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig configures a backend's circuit breaker. The breaker is off
+// while FailureThreshold is 0.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed requests (round
+	// trip errors or 5xx) that opens the circuit.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before trial requests
+	// are let through.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is how many trial requests may run at once while half
+	// open, and how many must succeed to close the circuit again.
+	HalfOpenRequests int
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int
+	trials    int
+	successes int
+}
+
+func (b *Backend) CircuitState() CircuitState {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.circuit.state
+}
+
+// circuitAvailable reports whether the breaker would let a request through.
+// Callers must hold b.mux.
+func (b *Backend) circuitAvailable() bool {
+	switch b.circuit.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		return b.circuit.trials < max(b.Breaker.HalfOpenRequests, 1)
+	default:
+		return true
+	}
+}
+
+// acquireCircuit asks the breaker to admit a request. trial is set when the
+// request is one of the half-open probes and must be passed back to
+// releaseCircuit.
+func (b *Backend) acquireCircuit() (ok, trial bool) {
+	if b.Breaker.FailureThreshold <= 0 {
+		return true, false
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if !b.circuitAvailable() {
+		return false, false
+	}
+	if b.circuit.state == CircuitHalfOpen {
+		b.circuit.trials++
+		return true, true
+	}
+	return true, false
+}
+
+// releaseCircuit records the outcome of a request admitted by acquireCircuit.
+// A request whose outcome says nothing about the backend, such as one the
+// client abandoned, only frees its trial slot.
+func (b *Backend) releaseCircuit(trial, counted, failed bool) {
+	if b.Breaker.FailureThreshold <= 0 {
+		return
+	}
+	b.setState(func() bool {
+		c := &b.circuit
+		if trial {
+			c.trials--
+		}
+		if !counted {
+			return false
+		}
+
+		switch {
+		case c.state == CircuitClosed && failed:
+			c.failures++
+			if c.failures >= b.Breaker.FailureThreshold {
+				b.openCircuit()
+				return true
+			}
+		case c.state == CircuitClosed:
+			c.failures = 0
+		case c.state == CircuitHalfOpen && trial && failed:
+			b.openCircuit()
+			return true
+		case c.state == CircuitHalfOpen && trial:
+			c.successes++
+			if c.successes >= max(b.Breaker.HalfOpenRequests, 1) {
+				*c = circuit{}
+				log.Printf("%s circuit [closed]\n", b.URL)
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// openCircuit trips the breaker and schedules the move to half-open. Callers
+// must hold b.mux.
+func (b *Backend) openCircuit() {
+	b.circuit = circuit{state: CircuitOpen, trials: b.circuit.trials}
+	log.Printf("%s circuit [open] for %s\n", b.URL, b.Breaker.OpenTimeout)
+	time.AfterFunc(b.Breaker.OpenTimeout, func() {
+		b.setState(func() bool {
+			if b.circuit.state != CircuitOpen {
+				return false
+			}
+			b.circuit.state = CircuitHalfOpen
+			b.circuit.successes = 0
+			log.Printf("%s circuit [half-open]\n", b.URL)
+			return true
+		})
+	})
+}
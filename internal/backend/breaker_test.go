@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func createBreakerBackend(t *testing.T, status *atomic.Int32, cfg BreakerConfig) *Backend {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+
+	u, _ := url.Parse(server.URL)
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, _ error) {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	b := New(u, proxy)
+	b.Breaker = cfg
+	return b
+}
+
+func proxyRequests(b *Backend, n int) {
+	for range n {
+		b.ReverseProxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+}
+
+func TestBreaker_DisabledByDefault(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	b := createBreakerBackend(t, &status, BreakerConfig{})
+
+	proxyRequests(b, 20)
+
+	if b.CircuitState() != CircuitClosed || !b.IsAvailable() {
+		t.Error("expected no breaker unless a failure threshold is set")
+	}
+
+	if b.Stats().Circuit != "" {
+		t.Errorf("expected no circuit in stats, got %q", b.Stats().Circuit)
+	}
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	b := createBreakerBackend(t, &status, BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Hour})
+
+	proxyRequests(b, 2)
+	if b.CircuitState() != CircuitClosed {
+		t.Fatal("expected circuit to stay closed below the threshold")
+	}
+
+	proxyRequests(b, 1)
+	if b.CircuitState() != CircuitOpen {
+		t.Fatalf("expected circuit to open, got %s", b.CircuitState())
+	}
+
+	if b.IsAvailable() {
+		t.Error("expected backend with an open circuit to be unavailable")
+	}
+
+	if b.Stats().Circuit != "open" {
+		t.Errorf("expected stats to report open, got %q", b.Stats().Circuit)
+	}
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	var status atomic.Int32
+	b := createBreakerBackend(t, &status, BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		status.Store(http.StatusInternalServerError)
+		proxyRequests(b, 2)
+		status.Store(http.StatusOK)
+		proxyRequests(b, 1)
+	}
+
+	if b.CircuitState() != CircuitClosed {
+		t.Errorf("expected interleaved successes to keep the circuit closed, got %s", b.CircuitState())
+	}
+}
+
+func TestBreaker_RejectsWhileOpen(t *testing.T) {
+	var status atomic.Int32
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	var proxyErr error
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		proxyErr = err
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	b := New(u, proxy)
+	b.Breaker = BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour}
+
+	status.Store(http.StatusInternalServerError)
+	proxyRequests(b, 1)
+	proxyRequests(b, 5)
+
+	if hits.Load() != 1 {
+		t.Errorf("expected requests to be rejected without reaching the backend, got %d hits", hits.Load())
+	}
+
+	if !errors.Is(proxyErr, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", proxyErr)
+	}
+}
+
+func TestBreaker_HalfOpenCloses(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	b := createBreakerBackend(t, &status, BreakerConfig{
+		FailureThreshold: 1,
+		OpenTimeout:      20 * time.Millisecond,
+		HalfOpenRequests: 2,
+	})
+
+	proxyRequests(b, 1)
+	time.Sleep(50 * time.Millisecond)
+
+	if b.CircuitState() != CircuitHalfOpen {
+		t.Fatalf("expected circuit to be half-open after the timeout, got %s", b.CircuitState())
+	}
+
+	status.Store(http.StatusOK)
+	proxyRequests(b, 1)
+	if b.CircuitState() != CircuitHalfOpen {
+		t.Fatal("expected circuit to need 2 successful trials")
+	}
+
+	proxyRequests(b, 1)
+	if b.CircuitState() != CircuitClosed {
+		t.Errorf("expected circuit to close, got %s", b.CircuitState())
+	}
+}
+
+func TestBreaker_HalfOpenFailureReopens(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	b := createBreakerBackend(t, &status, BreakerConfig{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond})
+
+	proxyRequests(b, 1)
+	time.Sleep(50 * time.Millisecond)
+	proxyRequests(b, 1)
+
+	if b.CircuitState() != CircuitOpen {
+		t.Errorf("expected failed trial to reopen the circuit, got %s", b.CircuitState())
+	}
+}
+
+func TestBreaker_HalfOpenLimitsTrials(t *testing.T) {
+	b := createTestBackend()
+	b.Breaker = BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour, HalfOpenRequests: 1}
+	b.circuit.state = CircuitHalfOpen
+
+	ok, trial := b.acquireCircuit()
+	if !ok || !trial {
+		t.Fatal("expected the first trial to be admitted")
+	}
+
+	if b.IsAvailable() {
+		t.Error("expected backend to be unavailable while its trial slots are taken")
+	}
+
+	if ok, _ := b.acquireCircuit(); ok {
+		t.Error("expected a second concurrent trial to be rejected")
+	}
+
+	b.releaseCircuit(trial, false, false)
+
+	if !b.IsAvailable() {
+		t.Error("expected trial slot to be freed")
+	}
+}
+
+func TestBreaker_NotifiesListeners(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	b := createBreakerBackend(t, &status, BreakerConfig{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond})
+
+	var calls atomic.Int32
+	b.OnStateChange(func(*Backend) { calls.Add(1) })
+
+	proxyRequests(b, 1)
+	time.Sleep(50 * time.Millisecond)
+
+	if calls.Load() != 2 {
+		t.Errorf("expected notifications for open and half-open, got %d", calls.Load())
+	}
+}
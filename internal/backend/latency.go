@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// latencyDecay is the weight given to each new sample in the moving average.
+const latencyDecay = 0.3
+
+type ewma struct {
+	bits atomic.Uint64
+}
+
+func (e *ewma) observe(sample float64) {
+	for {
+		old := e.bits.Load()
+		next := sample
+		if old != 0 {
+			prev := math.Float64frombits(old)
+			next = prev + latencyDecay*(sample-prev)
+		}
+		if e.bits.CompareAndSwap(old, math.Float64bits(next)) {
+			return
+		}
+	}
+}
+
+func (e *ewma) value() float64 {
+	return math.Float64frombits(e.bits.Load())
+}
+
+// transport wraps the reverse proxy's round tripper so every upstream round
+// trip passes the backend's circuit breaker and feeds its latency average and
+// response listeners.
+type transport struct {
+	backend *Backend
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ok, trial := t.backend.acquireCircuit()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.backend.ObserveLatency(time.Since(start))
+		t.backend.observeResponse(resp.StatusCode, nil)
+		t.backend.releaseCircuit(trial, true, resp.StatusCode >= 500)
+	} else if req.Context().Err() == nil {
+		t.backend.observeResponse(0, err)
+		t.backend.releaseCircuit(trial, true, true)
+	} else {
+		// A client that went away says nothing about the backend.
+		t.backend.releaseCircuit(trial, false, false)
+	}
+	return resp, err
+}
@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestObserveLatency_FirstSample(t *testing.T) {
+	b := createTestBackend()
+
+	if b.LatencyEWMA() != 0 {
+		t.Errorf("expected no latency before any sample, got %s", b.LatencyEWMA())
+	}
+
+	b.ObserveLatency(100 * time.Millisecond)
+
+	if b.LatencyEWMA() != 100*time.Millisecond {
+		t.Errorf("expected first sample to seed the average, got %s", b.LatencyEWMA())
+	}
+}
+
+func TestObserveLatency_Decays(t *testing.T) {
+	b := createTestBackend()
+
+	b.ObserveLatency(100 * time.Millisecond)
+	b.ObserveLatency(200 * time.Millisecond)
+
+	expected := 130 * time.Millisecond
+	if b.LatencyEWMA() != expected {
+		t.Errorf("expected %s, got %s", expected, b.LatencyEWMA())
+	}
+}
+
+func TestObserveLatency_Concurrent(t *testing.T) {
+	b := createTestBackend()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.ObserveLatency(10 * time.Millisecond)
+		}()
+	}
+
+	wg.Wait()
+
+	if b.LatencyEWMA() != 10*time.Millisecond {
+		t.Errorf("expected 10ms, got %s", b.LatencyEWMA())
+	}
+}
+
+func TestReverseProxy_RecordsLatency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := New(u, httputil.NewSingleHostReverseProxy(u))
+
+	w := httptest.NewRecorder()
+	b.ReverseProxy.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if b.LatencyEWMA() < 20*time.Millisecond {
+		t.Errorf("expected latency of at least 20ms, got %s", b.LatencyEWMA())
+	}
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestReverseProxy_IgnoresFailedRoundTrips(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8080")
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	proxy.Transport = failingTransport{}
+	proxy.ErrorHandler = func(http.ResponseWriter, *http.Request, error) {}
+	b := New(u, proxy)
+
+	b.ReverseProxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if b.LatencyEWMA() != 0 {
+		t.Errorf("expected failed round trip not to be recorded, got %s", b.LatencyEWMA())
+	}
+}
+
+func TestReverseProxy_NotifiesResponseListeners(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := New(u, httputil.NewSingleHostReverseProxy(u))
+
+	var status int
+	cancel := b.OnResponse(func(_ *Backend, s int, err error) {
+		status = s
+	})
+
+	b.ReverseProxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("expected listener to see 503, got %d", status)
+	}
+
+	cancel()
+	status = 0
+	b.ReverseProxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if status != 0 {
+		t.Error("expected no notification after unsubscribing")
+	}
+}
+
+func TestReverseProxy_NotifiesRoundTripErrors(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8080")
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	proxy.Transport = failingTransport{}
+	proxy.ErrorHandler = func(http.ResponseWriter, *http.Request, error) {}
+	b := New(u, proxy)
+
+	var got error
+	b.OnResponse(func(_ *Backend, _ int, err error) {
+		got = err
+	})
+
+	b.ReverseProxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if got == nil {
+		t.Error("expected listener to receive the round trip error")
+	}
+}
@@ -0,0 +1,160 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Forwarding headers a Resolver can read the client chain from.
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded"
+)
+
+// Resolver finds the original client address of a request. Forwarding
+// headers are only believed when they were added by one of the trusted
+// proxies, walking the chain from the nearest hop outwards.
+type Resolver struct {
+	header  string
+	trusted []*net.IPNet
+}
+
+// NewResolver accepts the forwarding header the trusted proxies maintain and
+// CIDRs or bare IPs of those proxies. Only that header is read: a proxy
+// appending to one header passes the other through from the client untouched,
+// so falling back between them would let clients spoof their address. An
+// empty header means X-Forwarded-For.
+func NewResolver(header string, trusted []string) (*Resolver, error) {
+	switch {
+	case header == "" || strings.EqualFold(header, HeaderXForwardedFor):
+		header = HeaderXForwardedFor
+	case strings.EqualFold(header, HeaderForwarded):
+		header = HeaderForwarded
+	default:
+		return nil, fmt.Errorf("unsupported client IP header %q, use %s or %s", header, HeaderXForwardedFor, HeaderForwarded)
+	}
+
+	r := &Resolver{header: header}
+	for _, s := range trusted {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", s)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			s = fmt.Sprintf("%s/%d", s, bits)
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+		r.trusted = append(r.trusted, n)
+	}
+	return r, nil
+}
+
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote := RemoteIP(req)
+	if r == nil || !r.isTrusted(remote) {
+		return remote
+	}
+
+	var hops []string
+	if r.header == HeaderForwarded {
+		hops = forwardedFor(req)
+	} else {
+		hops = xForwardedFor(req)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseHop(hops[i])
+		if ip == "" {
+			break
+		}
+		client = ip
+		if !r.isTrusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+func (r *Resolver) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range r.trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP is the address of the peer that opened the connection.
+func RemoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the chain of the standard Forwarded header, client
+// first.
+func forwardedFor(req *http.Request) []string {
+	var hops []string
+	for _, v := range req.Header.Values(HeaderForwarded) {
+		for elem := range strings.SplitSeq(v, ",") {
+			for pair := range strings.SplitSeq(elem, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, strings.Trim(value, `"`))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// xForwardedFor returns the chain of the X-Forwarded-For header, client
+// first.
+func xForwardedFor(req *http.Request) []string {
+	var hops []string
+	for _, v := range req.Header.Values(HeaderXForwardedFor) {
+		for hop := range strings.SplitSeq(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseHop extracts the IP from a forwarding entry such as "203.0.113.7",
+// "203.0.113.7:4711" or "[2001:db8::1]:4711". Obfuscated or unknown entries
+// yield "".
+func parseHop(hop string) string {
+	if ip := net.ParseIP(hop); ip != nil {
+		return ip.String()
+	}
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return ip.String()
+		}
+	}
+	if strings.HasPrefix(hop, "[") && strings.HasSuffix(hop, "]") {
+		if ip := net.ParseIP(hop[1 : len(hop)-1]); ip != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewResolver_Invalid(t *testing.T) {
+	for _, s := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := NewResolver(HeaderXForwardedFor, []string{s}); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestNewResolver_InvalidHeader(t *testing.T) {
+	if _, err := NewResolver("X-Real-IP", nil); err == nil {
+		t.Error("expected error for unsupported header")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"}
+
+	testCases := []struct {
+		name     string
+		header   string
+		remote   string
+		xff      string
+		fwd      string
+		expected string
+	}{
+		{
+			name:     "no proxy",
+			remote:   "203.0.113.7:5000",
+			expected: "203.0.113.7",
+		},
+		{
+			name:     "untrusted peer headers ignored",
+			remote:   "203.0.113.7:5000",
+			xff:      "1.2.3.4",
+			expected: "203.0.113.7",
+		},
+		{
+			name:     "trusted peer",
+			remote:   "10.1.2.3:5000",
+			xff:      "198.51.100.9",
+			expected: "198.51.100.9",
+		},
+		{
+			name:     "chain of trusted proxies",
+			remote:   "10.1.2.3:5000",
+			xff:      "198.51.100.9, 192.168.1.1, 10.9.9.9",
+			expected: "198.51.100.9",
+		},
+		{
+			name:     "spoofed entry before untrusted hop",
+			remote:   "10.1.2.3:5000",
+			xff:      "6.6.6.6, 198.51.100.9",
+			expected: "198.51.100.9",
+		},
+		{
+			name:     "all hops trusted",
+			remote:   "10.1.2.3:5000",
+			xff:      "10.0.0.1, 10.0.0.2",
+			expected: "10.0.0.1",
+		},
+		{
+			name:     "garbage hop stops the walk",
+			remote:   "10.1.2.3:5000",
+			xff:      "198.51.100.9, garbage",
+			expected: "10.1.2.3",
+		},
+		{
+			name:     "forwarded header",
+			header:   HeaderForwarded,
+			remote:   "10.1.2.3:5000",
+			fwd:      `for=198.51.100.9;proto=https, for="[2001:db8::1]:4711"`,
+			expected: "198.51.100.9",
+		},
+		{
+			name:     "client forwarded header ignored behind x-forwarded-for proxy",
+			remote:   "10.0.0.5:5000",
+			xff:      "203.0.113.9",
+			fwd:      "for=6.6.6.6",
+			expected: "203.0.113.9",
+		},
+		{
+			name:     "client x-forwarded-for ignored behind forwarded proxy",
+			header:   HeaderForwarded,
+			remote:   "10.0.0.5:5000",
+			xff:      "6.6.6.6",
+			fwd:      "for=203.0.113.9",
+			expected: "203.0.113.9",
+		},
+		{
+			name:     "no fallback to x-forwarded-for",
+			header:   HeaderForwarded,
+			remote:   "10.0.0.5:5000",
+			xff:      "6.6.6.6",
+			expected: "10.0.0.5",
+		},
+		{
+			name:     "forwarded with port",
+			header:   HeaderForwarded,
+			remote:   "10.1.2.3:5000",
+			fwd:      `for="198.51.100.11:8443"`,
+			expected: "198.51.100.11",
+		},
+		{
+			name:     "ipv6 peer",
+			remote:   "[2001:db8::5]:5000",
+			xff:      "198.51.100.12",
+			expected: "198.51.100.12",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewResolver(tc.header, trusted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remote
+			if tc.xff != "" {
+				req.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			if tc.fwd != "" {
+				req.Header.Set("Forwarded", tc.fwd)
+			}
+
+			if got := r.ClientIP(req); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestClientIP_NilResolver(t *testing.T) {
+	var r *Resolver
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if got := r.ClientIP(req); got != "10.1.2.3" {
+		t.Errorf("expected remote address, got %s", got)
+	}
+}
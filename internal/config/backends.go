@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/eltoncampos/load-balancer/internal/healthcheck"
+)
+
+// BackendSpec is one entry of the -backends list, written as the backend URL
+// followed by optional "|key=value" options, e.g. "http://host:8080|weight=5".
+type BackendSpec struct {
+	URL        *url.URL
+	Weight     int
+	Priority   int
+	Zone       string
+	Prober     healthcheck.Prober
+	Rise       int
+	Fall       int
+	HealthAddr string
+}
+
+// BackupPriority is the tier "backup" entries are placed in; primaries are 0.
+const BackupPriority = 1
+
+func ParseBackends(list string) ([]BackendSpec, error) {
+	specs := make([]BackendSpec, 0)
+	for tok := range strings.SplitSeq(list, ",") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		spec, err := ParseBackend(tok)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no backends configured")
+	}
+	return specs, nil
+}
+
+func ParseBackend(s string) (BackendSpec, error) {
+	parts := strings.Split(s, "|")
+
+	u, err := url.Parse(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return BackendSpec{}, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return BackendSpec{}, fmt.Errorf("invalid backend URL %q", parts[0])
+	}
+
+	spec := BackendSpec{
+		URL:    u,
+		Weight: 1,
+		Rise:   1,
+		Fall:   1,
+	}
+
+	health := make(map[string]string)
+	for _, opt := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "health":
+			health["type"] = value
+		case "health-rise", "health-fall":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return BackendSpec{}, fmt.Errorf("%s: %s must be a positive integer, got %q", u, key, value)
+			}
+			if key == "health-rise" {
+				spec.Rise = n
+			} else {
+				spec.Fall = n
+			}
+		case "health-addr":
+			if _, port, err := net.SplitHostPort(value); err != nil || !validPort(port) {
+				return BackendSpec{}, fmt.Errorf("%s: health-addr must be host:port, got %q", u, value)
+			}
+			spec.HealthAddr = value
+		case "health-port":
+			if !validPort(value) {
+				return BackendSpec{}, fmt.Errorf("%s: health-port must be a port number, got %q", u, value)
+			}
+			spec.HealthAddr = net.JoinHostPort("", value)
+		case "weight":
+			w, err := strconv.Atoi(value)
+			if err != nil || w <= 0 {
+				return BackendSpec{}, fmt.Errorf("%s: weight must be a positive integer, got %q", u, value)
+			}
+			spec.Weight = w
+		case "priority":
+			p, err := strconv.Atoi(value)
+			if err != nil || p < 0 {
+				return BackendSpec{}, fmt.Errorf("%s: priority must be a non-negative integer, got %q", u, value)
+			}
+			spec.Priority = p
+		case "zone":
+			if value == "" {
+				return BackendSpec{}, fmt.Errorf("%s: zone must not be empty", u)
+			}
+			spec.Zone = value
+		case "backup":
+			spec.Priority = BackupPriority
+		default:
+			name, ok := strings.CutPrefix(key, "health-")
+			if !ok {
+				return BackendSpec{}, fmt.Errorf("%s: unknown backend option %q", u, key)
+			}
+			health[name] = value
+		}
+	}
+
+	if len(health) > 0 {
+		p, err := healthcheck.ParseProber(health)
+		if err != nil {
+			return BackendSpec{}, fmt.Errorf("%s: %w", u, err)
+		}
+		spec.Prober = p
+	}
+
+	return spec, nil
+}
+
+func validPort(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 0 && n <= 65535
+}
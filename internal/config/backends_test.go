@@ -0,0 +1,194 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/healthcheck"
+)
+
+func TestParseBackend_PlainURL(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.URL.String() != "http://localhost:8080" {
+		t.Errorf("expected URL http://localhost:8080, got %s", spec.URL)
+	}
+
+	if spec.Weight != 1 {
+		t.Errorf("expected default weight 1, got %d", spec.Weight)
+	}
+}
+
+func TestParseBackend_WithWeight(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080|weight=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Weight != 5 {
+		t.Errorf("expected weight 5, got %d", spec.Weight)
+	}
+}
+
+func TestParseBackend_Priority(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected int
+	}{
+		{spec: "http://localhost:8080", expected: 0},
+		{spec: "http://localhost:8080|priority=2", expected: 2},
+		{spec: "http://localhost:8080|backup", expected: BackupPriority},
+		{spec: "http://localhost:8080|weight=3|backup", expected: BackupPriority},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			spec, err := ParseBackend(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if spec.Priority != tc.expected {
+				t.Errorf("expected priority %d, got %d", tc.expected, spec.Priority)
+			}
+		})
+	}
+}
+
+func TestParseBackend_Zone(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080|zone=us-east-1a|weight=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Zone != "us-east-1a" {
+		t.Errorf("expected zone us-east-1a, got %q", spec.Zone)
+	}
+}
+
+func TestParseBackend_HealthCheck(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080|health=http|health-path=/healthz|health-status=200-204;301|weight=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := spec.Prober.(*healthcheck.HTTPProber)
+	if !ok {
+		t.Fatalf("expected HTTP prober, got %T", spec.Prober)
+	}
+
+	if p.Path != "/healthz" || len(p.Statuses) != 2 {
+		t.Errorf("unexpected prober %+v", p)
+	}
+
+	if spec.Weight != 2 {
+		t.Errorf("expected other options to still apply, got weight %d", spec.Weight)
+	}
+}
+
+func TestParseBackend_DefaultHealthCheck(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Prober != nil {
+		t.Errorf("expected no prober override, got %T", spec.Prober)
+	}
+}
+
+func TestParseBackend_RiseFall(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080|health-rise=2|health-fall=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Rise != 2 || spec.Fall != 3 {
+		t.Errorf("expected rise 2 and fall 3, got %d and %d", spec.Rise, spec.Fall)
+	}
+
+	if spec.Prober != nil {
+		t.Errorf("expected thresholds not to override the prober, got %T", spec.Prober)
+	}
+}
+
+func TestParseBackend_HealthAddr(t *testing.T) {
+	spec, err := ParseBackend("http://localhost:8080|health-addr=10.0.0.5:9090")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.HealthAddr != "10.0.0.5:9090" {
+		t.Errorf("expected health address 10.0.0.5:9090, got %q", spec.HealthAddr)
+	}
+
+	spec, err = ParseBackend("http://localhost:8080|health-port=9090|health=http")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.HealthAddr != ":9090" {
+		t.Errorf("expected port-only health address, got %q", spec.HealthAddr)
+	}
+
+	if _, ok := spec.Prober.(*healthcheck.HTTPProber); !ok {
+		t.Errorf("expected HTTP prober alongside the override, got %T", spec.Prober)
+	}
+}
+
+func TestParseBackend_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec string
+	}{
+		{name: "missing scheme", spec: "localhost:8080"},
+		{name: "zero weight", spec: "http://localhost:8080|weight=0"},
+		{name: "negative weight", spec: "http://localhost:8080|weight=-1"},
+		{name: "non numeric weight", spec: "http://localhost:8080|weight=big"},
+		{name: "unknown option", spec: "http://localhost:8080|color=blue"},
+		{name: "empty zone", spec: "http://localhost:8080|zone="},
+		{name: "unknown health type", spec: "http://localhost:8080|health=icmp"},
+		{name: "bad health option", spec: "http://localhost:8080|health=http|health-status=abc"},
+		{name: "negative priority", spec: "http://localhost:8080|priority=-1"},
+		{name: "non numeric priority", spec: "http://localhost:8080|priority=high"},
+		{name: "zero rise", spec: "http://localhost:8080|health-rise=0"},
+		{name: "health addr without port", spec: "http://localhost:8080|health-addr=10.0.0.5"},
+		{name: "health port out of range", spec: "http://localhost:8080|health-port=70000"},
+		{name: "non numeric fall", spec: "http://localhost:8080|health-fall=many"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseBackend(tc.spec); err == nil {
+				t.Errorf("expected error for %q", tc.spec)
+			}
+		})
+	}
+}
+
+func TestParseBackends(t *testing.T) {
+	specs, err := ParseBackends("http://a:80|weight=3, http://b:80,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 backends, got %d", len(specs))
+	}
+
+	if specs[0].URL.Host != "a:80" || specs[0].Weight != 3 {
+		t.Errorf("unexpected first backend %+v", specs[0])
+	}
+
+	if specs[1].URL.Host != "b:80" || specs[1].Weight != 1 {
+		t.Errorf("unexpected second backend %+v", specs[1])
+	}
+}
+
+func TestParseBackends_Empty(t *testing.T) {
+	if _, err := ParseBackends(" , "); err == nil {
+		t.Error("expected error for empty backend list")
+	}
+}
@@ -3,25 +3,168 @@ package config
 import (
 	"flag"
 	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/internal/clientip"
+	"github.com/eltoncampos/load-balancer/internal/handler"
+	"github.com/eltoncampos/load-balancer/internal/healthcheck"
+	"github.com/eltoncampos/load-balancer/internal/pool"
 )
 
 type Config struct {
-	Port        int
-	ServerList  string
-	HealthCheckInterval int
+	Port                 int
+	ServerList           string
+	HealthCheckInterval  int
+	HealthCheckWorkers   int
+	HealthCheckJitter    float64
+	HealthWebhook        string
+	HealthWebhookRetries int
+	Strategy             string
+	Backends             []BackendSpec
+	HashKey              string
+	HashReplicas         int
+	HashLoadFactor       float64
+	StickySessions       bool
+	StickySecret         string
+	TrustedProxies       []string
+	ClientIPHeader       string
+	AdminPort            int
+	AdminAddr            string
+	DrainTimeout         int
+	SlowStart            int
+	Zone                 string
+	SpilloverThreshold   float64
+	OutlierDetection     bool
+	Outlier              pool.OutlierConfig
+	Breaker              backend.BreakerConfig
+	RetryRoutes          []string
+	RetryBudgetRatio     float64
+	RetryBudgetMin       int
+	RetryBudgetWindow    time.Duration
+	RetryMaxBody         int64
 }
 
 func Load() *Config {
 	cfg := &Config{}
 
-	flag.StringVar(&cfg.ServerList, "backends", "", "Load balanced backends, use commas to separate")
+	flag.StringVar(&cfg.ServerList, "backends", "", "Load balanced backends, use commas to separate (options: url|weight=N|priority=N|backup|zone=Z|health=tcp/http/grpc|health-<option>=V)")
 	flag.IntVar(&cfg.Port, "port", 3030, "Port to serve")
 	flag.IntVar(&cfg.HealthCheckInterval, "health-check-interval", 20, "Health check interval in seconds")
+	flag.IntVar(&cfg.HealthCheckWorkers, "health-check-workers", healthcheck.DefaultWorkers, "Maximum number of health probes run at once")
+	flag.Float64Var(&cfg.HealthCheckJitter, "health-check-jitter", healthcheck.DefaultJitter, "Fraction of the interval each probe is randomly delayed by")
+	flag.StringVar(&cfg.HealthWebhook, "health-webhook", "", "URL that receives a JSON POST whenever a backend goes up or down")
+	flag.IntVar(&cfg.HealthWebhookRetries, "health-webhook-retries", healthcheck.DefaultWebhookRetries, "Retries for a failed health webhook delivery")
+	flag.StringVar(&cfg.Strategy, "strategy", pool.RoundRobinStrategy, "Balancing strategy ("+strings.Join(pool.Strategies(), ", ")+")")
+	flag.StringVar(&cfg.HashKey, "hash-key", "path", "Request attribute hashed by hash strategies (path, ip, header:<name>, query:<name>)")
+	flag.IntVar(&cfg.HashReplicas, "hash-replicas", pool.DefaultReplicas, "Virtual nodes per backend on the hash ring")
+	flag.Float64Var(&cfg.HashLoadFactor, "hash-load-factor", pool.DefaultLoadFactor, "Max in-flight load per backend relative to the average, for bounded-hash")
+	flag.BoolVar(&cfg.StickySessions, "sticky-sessions", false, "Pin clients to a backend with a signed cookie")
+	flag.StringVar(&cfg.StickySecret, "sticky-secret", "", "Secret used to sign sticky session cookies (random if empty)")
+	flag.IntVar(&cfg.AdminPort, "admin-port", 0, "Port for the admin API, 0 disables it")
+	flag.StringVar(&cfg.AdminAddr, "admin-addr", "127.0.0.1", "Address the admin API listens on; it has no authentication, so keep it private")
+	flag.IntVar(&cfg.DrainTimeout, "drain-timeout", 30, "Seconds to wait for in-flight requests before removing a draining backend")
+	flag.IntVar(&cfg.SlowStart, "slow-start", 0, "Seconds over which a recovered or new backend ramps up to its full weight, 0 disables")
+	flag.StringVar(&cfg.Zone, "zone", "", "Zone this instance runs in; same-zone backends are preferred")
+	flag.Float64Var(&cfg.SpilloverThreshold, "zone-spillover-threshold", pool.DefaultSpilloverThreshold, "Healthy fraction of local backends below which traffic starts spilling to other zones")
+	outlier := pool.DefaultOutlierConfig()
+	flag.BoolVar(&cfg.OutlierDetection, "outlier-detection", false, "Eject backends that fail proxied requests")
+	flag.IntVar(&outlier.Consecutive5xx, "outlier-consecutive-5xx", outlier.Consecutive5xx, "Consecutive 5xx responses or errors that eject a backend, 0 disables")
+	flag.IntVar(&outlier.ConsecutiveGatewayErrors, "outlier-consecutive-gateway-errors", outlier.ConsecutiveGatewayErrors, "Consecutive 502/503/504 responses or errors that eject a backend, 0 disables")
+	flag.Float64Var(&outlier.ErrorRate, "outlier-error-rate", outlier.ErrorRate, "Failed fraction of requests within a window that ejects a backend, 0 disables")
+	flag.IntVar(&outlier.MinRequests, "outlier-min-requests", outlier.MinRequests, "Requests needed in a window before the error rate is considered")
+	flag.DurationVar(&outlier.Window, "outlier-window", outlier.Window, "Window over which the error rate is measured")
+	flag.DurationVar(&outlier.BaseEjectionTime, "outlier-base-ejection-time", outlier.BaseEjectionTime, "Ejection time, doubled for every repeated ejection")
+	flag.DurationVar(&outlier.MaxEjectionTime, "outlier-max-ejection-time", outlier.MaxEjectionTime, "Upper bound on the ejection time")
+	flag.IntVar(&outlier.MaxEjectionPercent, "outlier-max-ejection-percent", outlier.MaxEjectionPercent, "Maximum percentage of backends ejected at once")
+	flag.IntVar(&cfg.Breaker.FailureThreshold, "circuit-breaker-failures", 0, "Consecutive failed requests that open a backend's circuit breaker, 0 disables")
+	flag.DurationVar(&cfg.Breaker.OpenTimeout, "circuit-breaker-timeout", 30*time.Second, "How long an open circuit rejects requests before allowing trial requests")
+	flag.IntVar(&cfg.Breaker.HalfOpenRequests, "circuit-breaker-half-open-requests", 1, "Concurrent trial requests while half-open, and successes needed to close")
+	retryRoutes := flag.String("retry-routes", "", "Comma separated path prefixes whose requests may be retried whatever their method")
+	flag.Float64Var(&cfg.RetryBudgetRatio, "retry-budget-ratio", handler.DefaultRetryRatio, "Maximum retries as a fraction of recent requests")
+	flag.IntVar(&cfg.RetryBudgetMin, "retry-budget-min-per-second", handler.DefaultRetryMinPerSecond, "Retries per second always allowed on top of the ratio")
+	flag.DurationVar(&cfg.RetryBudgetWindow, "retry-budget-window", handler.DefaultRetryWindow, "Window over which requests and retries are counted")
+	flag.Int64Var(&cfg.RetryMaxBody, "retry-max-body", handler.DefaultMaxRetryBody, "Largest request body in bytes buffered so it can be resent on retry (0 disables retrying requests with a body)")
+	trustedProxies := flag.String("trusted-proxies", "", "Comma separated CIDRs of proxies whose client IP header is trusted")
+	flag.StringVar(&cfg.ClientIPHeader, "client-ip-header", clientip.HeaderXForwardedFor, "Forwarding header the trusted proxies set (X-Forwarded-For or Forwarded); the other one is ignored")
 	flag.Parse()
 
 	if len(cfg.ServerList) == 0 {
 		log.Fatal("Please provide one or more backends to load balance")
 	}
 
+	if *retryRoutes != "" {
+		cfg.RetryRoutes = strings.Split(*retryRoutes, ",")
+	}
+
+	if *trustedProxies != "" {
+		cfg.TrustedProxies = strings.Split(*trustedProxies, ",")
+	}
+
+	backends, err := ParseBackends(cfg.ServerList)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Backends = backends
+
+	if !slices.Contains(pool.Strategies(), cfg.Strategy) {
+		log.Fatalf("Unknown balancing strategy %q, available: %s", cfg.Strategy, strings.Join(pool.Strategies(), ", "))
+	}
+
+	if _, err := pool.ParseHashKey(cfg.HashKey, nil); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.HashReplicas <= 0 {
+		log.Fatal("Hash replicas must be positive")
+	}
+
+	if cfg.HealthCheckWorkers <= 0 {
+		log.Fatal("Health check workers must be positive")
+	}
+
+	if cfg.HealthCheckJitter < 0 || cfg.HealthCheckJitter >= 1 {
+		log.Fatal("Health check jitter must be at least 0 and below 1")
+	}
+
+	if outlier.ErrorRate < 0 || outlier.ErrorRate > 1 {
+		log.Fatal("Outlier error rate must be between 0 and 1")
+	}
+
+	if outlier.BaseEjectionTime <= 0 || outlier.MaxEjectionTime < outlier.BaseEjectionTime {
+		log.Fatal("Outlier ejection times must be positive, with the maximum at least the base")
+	}
+
+	if outlier.MaxEjectionPercent < 0 || outlier.MaxEjectionPercent > 100 {
+		log.Fatal("Outlier max ejection percent must be between 0 and 100")
+	}
+	cfg.Outlier = outlier
+
+	if cfg.Breaker.FailureThreshold < 0 || cfg.Breaker.OpenTimeout <= 0 || cfg.Breaker.HalfOpenRequests <= 0 {
+		log.Fatal("Circuit breaker failures must not be negative, and its timeout and half-open requests must be positive")
+	}
+
+	if cfg.HealthWebhookRetries < 0 {
+		log.Fatal("Health webhook retries must not be negative")
+	}
+
+	if cfg.RetryBudgetRatio < 0 || cfg.RetryBudgetMin < 0 || cfg.RetryBudgetWindow <= 0 {
+		log.Fatal("Retry budget ratio and minimum must not be negative, and its window must be positive")
+	}
+
+	if cfg.RetryMaxBody < 0 {
+		log.Fatal("Retry max body must not be negative")
+	}
+
+	if cfg.SpilloverThreshold < 0 || cfg.SpilloverThreshold > 1 {
+		log.Fatal("Zone spillover threshold must be between 0 and 1")
+	}
+
+	if cfg.HashLoadFactor < 1 {
+		log.Fatal("Hash load factor must be at least 1")
+	}
+
 	return cfg
 }
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxRetryBody is the largest request body buffered for replay on
+// retries.
+const DefaultMaxRetryBody = 1 << 20
+
+// WithMaxRetryBody sets how many bytes of a request body are buffered so
+// retries can resend it. Requests with larger bodies are streamed and not
+// retried; 0 disables buffering.
+func WithMaxRetryBody(n int64) Option {
+	return func(lb *LoadBalancer) {
+		lb.maxRetryBody = n
+	}
+}
+
+// bufferBody returns a copy of r whose body has been read into memory, up to
+// maxRetryBody, with GetBody set so each retry can start from the beginning.
+// A larger body is stitched back together and streamed, leaving GetBody unset
+// so the request is never replayed.
+func (lb *LoadBalancer) bufferBody(r *http.Request) (*http.Request, error) {
+	if !hasBody(r) || !lb.Retryable(r) || lb.maxRetryBody <= 0 {
+		return r, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, lb.maxRetryBody+1))
+	if err != nil {
+		return r, err
+	}
+
+	body := r.Body
+	r = r.WithContext(r.Context())
+	if int64(len(buf)) > lb.maxRetryBody {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), body), body}
+		return r, nil
+	}
+
+	_ = body.Close()
+	r.ContentLength = int64(len(buf))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	r.Body, _ = r.GetBody()
+	return r, nil
+}
+
+// rewindBody returns a copy of a retried request with a fresh reader over its
+// buffered body.
+func rewindBody(r *http.Request) *http.Request {
+	if r.GetBody == nil {
+		return r
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return r
+	}
+	r = r.WithContext(r.Context())
+	r.Body = body
+	return r
+}
+
+// replayable reports whether r's body, if any, can be sent again.
+func replayable(r *http.Request) bool {
+	return !hasBody(r) || r.GetBody != nil
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// createDroppingServer reads the whole request body and then drops the
+// connection, so the proxy sees a transport error after the body is gone.
+func createDroppingServer(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// createEchoServer records every body it receives and answers 200.
+func createEchoServer(t *testing.T, bodies chan<- string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// createRetryingBalancer returns a balancer whose first pick drops the
+// connection after reading the body and whose second records what it gets.
+func createRetryingBalancer(t *testing.T, opts ...Option) (*LoadBalancer, *atomic.Int32, chan string) {
+	hits := &atomic.Int32{}
+	bodies := make(chan string, 10)
+	failing := createTestBackend(createDroppingServer(t, hits).URL)
+	healthy := createTestBackend(createEchoServer(t, bodies).URL)
+
+	// Round robin starts at the second backend.
+	lb := New(createTestPool(healthy, failing), opts...)
+	for _, b := range []*backend.Backend{failing, healthy} {
+		b.ReverseProxy.ErrorHandler = lb.CreateErrorHandler()
+	}
+	return lb, hits, bodies
+}
+
+func TestServeHTTP_RetryResendsBody(t *testing.T) {
+	lb, hits, bodies := createRetryingBalancer(t)
+	payload := `{"order": 42, "items": ["a", "b"]}`
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/42", strings.NewReader(payload)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected retry to succeed, got %d", w.Code)
+	}
+
+	if hits.Load() != 1 {
+		t.Fatalf("expected the first attempt to fail, got %d hits", hits.Load())
+	}
+
+	if got := <-bodies; got != payload {
+		t.Errorf("expected second backend to receive %q, got %q", payload, got)
+	}
+}
+
+func TestServeHTTP_RetryResendsBodyWithIdempotencyKey(t *testing.T) {
+	lb, hits, bodies := createRetryingBalancer(t)
+	payload := strings.Repeat("x", 64<<10)
+
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(payload))
+	req.Header.Set(IdempotencyKeyHeader, "pay-1")
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected retry to succeed, got %d", w.Code)
+	}
+
+	if hits.Load() != 1 {
+		t.Fatalf("expected the first attempt to fail, got %d hits", hits.Load())
+	}
+
+	if got := <-bodies; got != payload {
+		t.Errorf("expected the full %d byte body, got %d bytes", len(payload), len(got))
+	}
+}
+
+func TestServeHTTP_LargeBodyIsNotRetried(t *testing.T) {
+	lb, hits, bodies := createRetryingBalancer(t, WithMaxRetryBody(16))
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/42", strings.NewReader(strings.Repeat("x", 17))))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+	}
+
+	if hits.Load() != 1 || len(bodies) != 0 {
+		t.Error("expected an unbuffered body not to be replayed")
+	}
+
+	if failing := lb.pool.GetBackends()[1]; failing.IsAlive() {
+		t.Error("expected the failing backend to be marked down without a retry")
+	}
+}
+
+func TestServeHTTP_LargeBodyIsStreamedIntact(t *testing.T) {
+	bodies := make(chan string, 1)
+	lb := New(createTestPool(createTestBackend(createEchoServer(t, bodies).URL)), WithMaxRetryBody(16))
+	payload := strings.Repeat("0123456789", 10)
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/orders/42", strings.NewReader(payload)))
+
+	if got := <-bodies; got != payload {
+		t.Errorf("expected body over the limit to be streamed intact, got %q", got)
+	}
+}
+
+func TestBufferBody_SkipsNonRetryableRequests(t *testing.T) {
+	lb := New(createTestPool())
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("data"))
+
+	buffered, err := lb.bufferBody(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buffered != req || buffered.GetBody != nil {
+		t.Error("expected a non-retryable request to be left untouched")
+	}
+}
+
+func TestBufferBody_DoesNotModifyOriginal(t *testing.T) {
+	lb := New(createTestPool())
+	req := httptest.NewRequest(http.MethodPut, "/orders/1", strings.NewReader("data"))
+	original := req.Body
+
+	buffered, err := lb.bufferBody(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Body != original || req.GetBody != nil {
+		t.Error("expected the incoming request not to be modified")
+	}
+
+	for i := 0; i < 2; i++ {
+		body, _ := io.ReadAll(rewindBody(buffered).Body)
+		if string(body) != "data" {
+			t.Errorf("expected rewind %d to yield the body, got %q", i+1, body)
+		}
+	}
+}
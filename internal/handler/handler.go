@@ -2,48 +2,131 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/internal/clientip"
 	"github.com/eltoncampos/load-balancer/internal/pool"
 )
 
 const (
 	Attempts int = iota
 	Retry
+	ClientIP
+	// releaseActive holds the func that releases the current attempt's
+	// active request count, so a retry can release it before re-entering.
+	releaseActive
+	// currentPeer holds the backend the current attempt was sent to.
+	currentPeer
 )
 
 type LoadBalancer struct {
-	pool *pool.ServerPool
+	pool         *pool.ServerPool
+	stickySecret []byte
+	resolver     *clientip.Resolver
+	retryRoutes  []string
+	retryBudget  *RetryBudget
+	maxRetryBody int64
 }
 
-func New(p *pool.ServerPool) *LoadBalancer {
-	return &LoadBalancer{
-		pool: p,
+type Option func(*LoadBalancer)
+
+func New(p *pool.ServerPool, opts ...Option) *LoadBalancer {
+	lb := &LoadBalancer{
+		pool:         p,
+		retryBudget:  NewRetryBudget(DefaultRetryRatio, DefaultRetryMinPerSecond, DefaultRetryWindow),
+		maxRetryBody: DefaultMaxRetryBody,
+	}
+	for _, opt := range opts {
+		opt(lb)
+	}
+	return lb
+}
+
+func WithClientIPResolver(r *clientip.Resolver) Option {
+	return func(lb *LoadBalancer) {
+		lb.resolver = r
 	}
 }
 
 func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(ClientIP).(string); !ok {
+		r = r.WithContext(context.WithValue(r.Context(), ClientIP, lb.resolver.ClientIP(r)))
+	}
+
+	if isRetry(r) {
+		if release, ok := r.Context().Value(releaseActive).(func()); ok {
+			release()
+		}
+		r = rewindBody(r)
+	} else {
+		lb.retryBudget.recordRequest()
+		var err error
+		if r, err = lb.bufferBody(r); err != nil {
+			log.Printf("%s(%s) Reading request body: %s\n", GetClientIPFromContext(r), r.URL.Path, err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+	}
+
 	attempts := GetAttemptsFromContext(r)
 	if attempts > 3 {
-		log.Printf("%s(%s) Max attempts reached, terminating\n", r.RemoteAddr, r.URL.Path)
-		http.Error(w, "Service not available", http.StatusServiceUnavailable)
+		log.Printf("%s(%s) Max attempts reached, terminating\n", GetClientIPFromContext(r), r.URL.Path)
+		Error(w, "Service not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	peer := lb.pool.GetNextPeer()
+	var peer *backend.Backend
+	if lb.stickyEnabled() {
+		peer = lb.pinnedPeer(r)
+	}
+	if peer == nil {
+		peer = lb.pool.GetPeer(r)
+		if peer != nil && lb.stickyEnabled() {
+			w = lb.pinOnResponse(w, peer)
+		}
+	}
+
 	if peer != nil {
-		peer.ReverseProxy.ServeHTTP(w, r)
+		// The error handler serves retries from inside the proxy call, so the
+		// count is released once the retry starts rather than when it ends.
+		peer.IncActive()
+		release := sync.OnceFunc(peer.DecActive)
+		defer release()
+		ctx := context.WithValue(r.Context(), releaseActive, release)
+		ctx = context.WithValue(ctx, currentPeer, peer)
+		peer.ReverseProxy.ServeHTTP(w, r.WithContext(ctx))
 		return
 	}
-	http.Error(w, "Service not available", http.StatusServiceUnavailable)
+	Error(w, "Service not available", http.StatusServiceUnavailable)
 }
 
 func (lb *LoadBalancer) CreateErrorHandler() func(http.ResponseWriter, *http.Request, error) {
 	return func(w http.ResponseWriter, req *http.Request, e error) {
 		log.Printf("[%s] %s\n", req.URL.Host, e.Error())
 
+		if errors.Is(e, backend.ErrCircuitOpen) {
+			// The breaker tripped after the backend was picked; it is not
+			// down, so move on to another one straight away.
+			attempts := GetAttemptsFromContext(req)
+			ctx := context.WithValue(req.Context(), Attempts, attempts+1)
+			lb.ServeHTTP(w, req.WithContext(ctx))
+			return
+		}
+
+		if !lb.AllowRetry(req) {
+			// The backend failed all the same, so it leaves the rotation
+			// even though this request cannot move on to another one.
+			lb.markFailed(req, "proxy request failed without retry", e)
+			log.Printf("%s(%s) Not retrying %s request\n", GetClientIPFromContext(req), req.URL.Path, req.Method)
+			Error(w, "Bad Gateway", http.StatusBadGateway)
+			return
+		}
+
 		retries := GetRetryFromContext(req)
 		if retries < 3 {
 			time.Sleep(10 * time.Millisecond)
@@ -53,16 +136,33 @@ func (lb *LoadBalancer) CreateErrorHandler() func(http.ResponseWriter, *http.Req
 			return
 		}
 
-		serverURL := req.URL
-		lb.pool.MarkBackendStatus(serverURL, false)
+		lb.markFailed(req, "proxy retries exhausted", e)
 
 		attempts := GetAttemptsFromContext(req)
-		log.Printf("%s(%s) Attempting retry %d\n", req.RemoteAddr, req.URL.Path, attempts)
+		log.Printf("%s(%s) Attempting retry %d\n", GetClientIPFromContext(req), req.URL.Path, attempts)
 		ctx := context.WithValue(req.Context(), Attempts, attempts+1)
 		lb.ServeHTTP(w, req.WithContext(ctx))
 	}
 }
 
+// markFailed marks the backend that failed req as down, giving reason and
+// the error. It is found through the attempt's context, or by URL when req
+// did not come through ServeHTTP.
+func (lb *LoadBalancer) markFailed(req *http.Request, reason string, e error) {
+	peer, ok := req.Context().Value(currentPeer).(*backend.Backend)
+	if !ok {
+		peer = lb.pool.GetBackend(req.URL)
+	}
+	if peer != nil {
+		peer.MarkDown(reason + ": " + e.Error())
+	}
+}
+
+// isRetry reports whether r is being served again after a failed attempt.
+func isRetry(r *http.Request) bool {
+	return r.Context().Value(Retry) != nil || r.Context().Value(Attempts) != nil
+}
+
 func GetAttemptsFromContext(r *http.Request) int {
 	if attempts, ok := r.Context().Value(Attempts).(int); ok {
 		return attempts
@@ -76,3 +176,10 @@ func GetRetryFromContext(r *http.Request) int {
 	}
 	return 0
 }
+
+func GetClientIPFromContext(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIP).(string); ok {
+		return ip
+	}
+	return clientip.RemoteIP(r)
+}
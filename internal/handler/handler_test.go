@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/internal/clientip"
 	"github.com/eltoncampos/load-balancer/internal/pool"
 	"github.com/eltoncampos/load-balancer/testutil"
 )
@@ -192,6 +193,33 @@ func TestCreateErrorHandler_WithRetriesOverLimit(t *testing.T) {
 	}
 }
 
+func TestCreateErrorHandler_CircuitOpenDoesNotMarkDown(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	p := createTestPool(b)
+	lb := New(p)
+
+	errorHandler := lb.CreateErrorHandler()
+
+	req := httptest.NewRequest("GET", server.URL, nil)
+	ctx := context.WithValue(req.Context(), Retry, 3)
+	req = req.WithContext(ctx)
+
+	w := httptest.NewRecorder()
+
+	errorHandler(w, req, backend.ErrCircuitOpen)
+
+	if !b.IsAlive() {
+		t.Error("expected an open circuit not to mark the backend down")
+	}
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected request to be served by another attempt, got %d", w.Code)
+	}
+}
+
 func TestGetAttemptsFromContext_WithValue(t *testing.T) {
 	req := httptest.NewRequest("GET", "/test", nil)
 	ctx := context.WithValue(req.Context(), Attempts, 5)
@@ -235,3 +263,123 @@ func TestGetRetryFromContext_WithoutValue(t *testing.T) {
 		t.Errorf("expected default 0 retries, got %d", retries)
 	}
 }
+
+func TestServeHTTP_TracksActiveRequests(t *testing.T) {
+	var b *backend.Backend
+	var during int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = b.ActiveRequests()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	b = createTestBackend(server.URL)
+	lb := New(createTestPool(b))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	w := httptest.NewRecorder()
+
+	lb.ServeHTTP(w, req)
+
+	if during != 1 {
+		t.Errorf("expected 1 active request while proxying, got %d", during)
+	}
+
+	if b.ActiveRequests() != 0 {
+		t.Errorf("expected 0 active requests after completion, got %d", b.ActiveRequests())
+	}
+}
+
+func TestServeHTTP_ReleasesActiveRequestsOnRetry(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	failing := createTestBackend("http://localhost:99999")
+	healthy := createTestBackend(server.URL)
+	p := createTestPool(failing, healthy)
+	lb := New(p)
+	failing.ReverseProxy.ErrorHandler = lb.CreateErrorHandler()
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+	}
+
+	if failing.ActiveRequests() != 0 || healthy.ActiveRequests() != 0 {
+		t.Errorf("expected all active counters released, got %d and %d", failing.ActiveRequests(), healthy.ActiveRequests())
+	}
+}
+
+func TestGetClientIPFromContext_WithValue(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	ctx := context.WithValue(req.Context(), ClientIP, "198.51.100.1")
+	req = req.WithContext(ctx)
+
+	if ip := GetClientIPFromContext(req); ip != "198.51.100.1" {
+		t.Errorf("expected 198.51.100.1, got %s", ip)
+	}
+}
+
+func TestGetClientIPFromContext_WithoutValue(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "203.0.113.5:4000"
+
+	if ip := GetClientIPFromContext(req); ip != "203.0.113.5" {
+		t.Errorf("expected remote address 203.0.113.5, got %s", ip)
+	}
+}
+
+func TestServeHTTP_ResolvesClientIPBehindTrustedProxy(t *testing.T) {
+	server := testutil.CreateTestServerSimple()
+	defer server.Close()
+
+	var seen string
+	b := createTestBackend(server.URL)
+	b.ReverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {}
+	director := b.ReverseProxy.Director
+	b.ReverseProxy.Director = func(r *http.Request) {
+		seen = GetClientIPFromContext(r)
+		director(r)
+	}
+
+	resolver, _ := clientip.NewResolver(clientip.HeaderXForwardedFor, []string{"10.0.0.0/8"})
+	lb := New(createTestPool(b), WithClientIPResolver(resolver))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.23")
+
+	lb.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen != "198.51.100.23" {
+		t.Errorf("expected resolved client IP in context, got %q", seen)
+	}
+}
+
+func TestServeHTTP_ReleasesFailedBackendDuringRetry(t *testing.T) {
+	var failing *backend.Backend
+	during := int64(-1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = failing.ActiveRequests()
+	}))
+	defer server.Close()
+
+	failing = createTestBackend("http://localhost:99999")
+	healthy := createTestBackend(server.URL)
+	lb := New(createTestPool(healthy, failing))
+	failing.ReverseProxy.ErrorHandler = lb.CreateErrorHandler()
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected retry to succeed, got %d", w.Code)
+	}
+
+	if during != 0 {
+		t.Errorf("expected the failed backend to read 0 active requests during the retry, got %d", during)
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader marks a request as safe to retry whatever its method.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	DefaultRetryRatio        = 0.2
+	DefaultRetryMinPerSecond = 10
+	DefaultRetryWindow       = 10 * time.Second
+)
+
+// retryBudgetBuckets is how many slices the budget window is split into.
+const retryBudgetBuckets = 10
+
+// idempotentMethods can be sent twice without changing the outcome
+// (RFC 9110, section 9.2.2).
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// WithRetryRoutes lets requests whose path is or lies below one of prefixes be
+// retried even when their method is not idempotent. Prefixes are trimmed and
+// empty ones skipped, so a stray comma in the flag cannot open every route.
+func WithRetryRoutes(prefixes ...string) Option {
+	return func(lb *LoadBalancer) {
+		lb.retryRoutes = nil
+		for _, prefix := range prefixes {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				lb.retryRoutes = append(lb.retryRoutes, prefix)
+			}
+		}
+	}
+}
+
+// WithRetryBudget replaces the default retry budget; nil removes the limit.
+func WithRetryBudget(b *RetryBudget) Option {
+	return func(lb *LoadBalancer) {
+		lb.retryBudget = b
+	}
+}
+
+// Retryable reports whether r may be sent to a backend again: its method is
+// idempotent, it carries an Idempotency-Key, or its route opted in.
+func (lb *LoadBalancer) Retryable(r *http.Request) bool {
+	if idempotentMethods[r.Method] || r.Header.Get(IdempotencyKeyHeader) != "" {
+		return true
+	}
+	for _, prefix := range lb.retryRoutes {
+		if underRoute(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// underRoute reports whether path is prefix or lies below it, matching whole
+// path segments only: "/api/search" covers "/api/search/products" but not
+// "/api/searchlog". An empty prefix matches nothing.
+func underRoute(path, prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || rest[0] == '/' || strings.HasSuffix(prefix, "/"))
+}
+
+// AllowRetry reports whether r may be retried now. It must be Retryable, its
+// body must have been buffered for replay and the retry budget must have
+// room; a granted retry is charged to the budget.
+func (lb *LoadBalancer) AllowRetry(r *http.Request) bool {
+	return lb.Retryable(r) && replayable(r) && lb.retryBudget.tryRetry()
+}
+
+// RetryBudget caps retries at a share of recent traffic so that a failing
+// backend cannot turn every request into several: over the last window,
+// retries may be at most ratio × requests plus minPerSecond × window.
+type RetryBudget struct {
+	ratio      float64
+	minRetries float64
+	bucketSize time.Duration
+
+	mux     sync.Mutex
+	buckets [retryBudgetBuckets]budgetBucket
+}
+
+type budgetBucket struct {
+	epoch    int64
+	requests int
+	retries  int
+}
+
+func NewRetryBudget(ratio float64, minPerSecond int, window time.Duration) *RetryBudget {
+	return &RetryBudget{
+		ratio:      ratio,
+		minRetries: float64(minPerSecond) * window.Seconds(),
+		bucketSize: max(window/retryBudgetBuckets, time.Millisecond),
+	}
+}
+
+func (b *RetryBudget) recordRequest() {
+	if b == nil {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.current().requests++
+}
+
+func (b *RetryBudget) tryRetry() bool {
+	if b == nil {
+		return true
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	cur := b.current()
+	requests, retries := 0, 0
+	for i := range b.buckets {
+		if b.buckets[i].epoch > cur.epoch-retryBudgetBuckets {
+			requests += b.buckets[i].requests
+			retries += b.buckets[i].retries
+		}
+	}
+	if float64(retries+1) > b.ratio*float64(requests)+b.minRetries {
+		return false
+	}
+	cur.retries++
+	return true
+}
+
+// current returns the bucket for now, clearing it if it last held an older
+// slice of time. Callers must hold b.mux.
+func (b *RetryBudget) current() *budgetBucket {
+	epoch := time.Now().UnixNano() / int64(b.bucketSize)
+	bucket := &b.buckets[epoch%retryBudgetBuckets]
+	if bucket.epoch != epoch {
+		*bucket = budgetBucket{epoch: epoch}
+	}
+	return bucket
+}
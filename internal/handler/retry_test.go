@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/pool"
+	"github.com/eltoncampos/load-balancer/testutil"
+)
+
+func TestRetryable(t *testing.T) {
+	lb := New(pool.New(), WithRetryRoutes("/api/search"))
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		expected bool
+	}{
+		{name: "GET", method: http.MethodGet, path: "/orders", expected: true},
+		{name: "HEAD", method: http.MethodHead, path: "/orders", expected: true},
+		{name: "PUT", method: http.MethodPut, path: "/orders/1", expected: true},
+		{name: "DELETE", method: http.MethodDelete, path: "/orders/1", expected: true},
+		{name: "POST", method: http.MethodPost, path: "/orders", expected: false},
+		{name: "PATCH", method: http.MethodPatch, path: "/orders/1", expected: false},
+		{name: "POST with idempotency key", method: http.MethodPost, path: "/orders", key: "abc-123", expected: true},
+		{name: "POST on opted-in route", method: http.MethodPost, path: "/api/search/products", expected: true},
+		{name: "POST on opted-in route itself", method: http.MethodPost, path: "/api/search", expected: true},
+		{name: "POST on route sharing the prefix", method: http.MethodPost, path: "/api/searchlog/delete", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set(IdempotencyKeyHeader, tc.key)
+			}
+
+			if got := lb.Retryable(req); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithRetryRoutes_IgnoresEmptyEntries(t *testing.T) {
+	// As produced by splitting "-retry-routes=/a,, /b,".
+	lb := New(pool.New(), WithRetryRoutes("/a", "", " /b", ""))
+
+	if lb.Retryable(httptest.NewRequest(http.MethodPost, "/orders", nil)) {
+		t.Error("expected an empty route entry not to make every POST retryable")
+	}
+	if !lb.Retryable(httptest.NewRequest(http.MethodPost, "/b/items", nil)) {
+		t.Error("expected a route with surrounding spaces to be trimmed and matched")
+	}
+}
+
+func TestRetryBudget_MinimumRetries(t *testing.T) {
+	b := NewRetryBudget(0, 1, 3*time.Second)
+
+	for i := 0; i < 3; i++ {
+		if !b.tryRetry() {
+			t.Fatalf("expected retry %d to fit the minimum", i+1)
+		}
+	}
+
+	if b.tryRetry() {
+		t.Error("expected the budget to be exhausted without traffic")
+	}
+}
+
+func TestRetryBudget_Ratio(t *testing.T) {
+	b := NewRetryBudget(0.1, 0, time.Minute)
+
+	for i := 0; i < 50; i++ {
+		b.recordRequest()
+	}
+
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		if b.tryRetry() {
+			allowed++
+		}
+	}
+
+	if allowed != 5 {
+		t.Errorf("expected 10%% of 50 requests to be retried, got %d", allowed)
+	}
+}
+
+func TestRetryBudget_WindowSlides(t *testing.T) {
+	b := NewRetryBudget(0, 10, 100*time.Millisecond)
+
+	for b.tryRetry() {
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if !b.tryRetry() {
+		t.Error("expected retries to be allowed again once the window moved on")
+	}
+}
+
+func TestRetryBudget_NilIsUnlimited(t *testing.T) {
+	var b *RetryBudget
+	b.recordRequest()
+
+	if !b.tryRetry() {
+		t.Error("expected a nil budget to allow retries")
+	}
+}
+
+func TestServeHTTP_CountsOriginalRequestsOnly(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	budget := NewRetryBudget(1, 0, time.Minute)
+	lb := New(createTestPool(createTestBackend(server.URL)), WithRetryBudget(budget))
+
+	lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), Retry, 1))
+	lb.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !budget.tryRetry() {
+		t.Fatal("expected one retry for the original request")
+	}
+
+	if budget.tryRetry() {
+		t.Error("expected the retried request not to add to the budget")
+	}
+}
+
+func TestCreateErrorHandler_DoesNotRetryPost(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	lb := New(createTestPool(createTestBackend(server.URL)))
+	errorHandler := lb.CreateErrorHandler()
+
+	w := httptest.NewRecorder()
+	errorHandler(w, httptest.NewRequest("POST", "/orders", nil), errors.New("test error"))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+	}
+
+	if calls != 0 {
+		t.Errorf("expected the POST not to be resent, got %d calls", calls)
+	}
+}
+
+func TestCreateErrorHandler_RetriesPostWithIdempotencyKey(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	lb := New(createTestPool(createTestBackend(server.URL)))
+	errorHandler := lb.CreateErrorHandler()
+
+	req := httptest.NewRequest("POST", "/orders", nil)
+	req.Header.Set(IdempotencyKeyHeader, "abc-123")
+	w := httptest.NewRecorder()
+	errorHandler(w, req, errors.New("test error"))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d after retry, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestCreateErrorHandler_RespectsRetryBudget(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	lb := New(createTestPool(createTestBackend(server.URL)), WithRetryBudget(NewRetryBudget(0, 0, time.Minute)))
+	errorHandler := lb.CreateErrorHandler()
+
+	w := httptest.NewRecorder()
+	errorHandler(w, httptest.NewRequest("GET", "/", nil), errors.New("test error"))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d once the budget is spent, got %d", http.StatusBadGateway, w.Code)
+	}
+}
+
+func TestCreateErrorHandler_MarksDownWithoutRetry(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		opts   []Option
+	}{
+		{name: "retry budget spent", method: http.MethodGet, opts: []Option{WithRetryBudget(NewRetryBudget(0, 0, time.Minute))}},
+		{name: "non-idempotent request", method: http.MethodPost},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.NotFoundHandler())
+			server.Close()
+
+			failing := createTestBackend(server.URL)
+			lb := New(createTestPool(failing), tc.opts...)
+			failing.ReverseProxy.ErrorHandler = lb.CreateErrorHandler()
+
+			w := httptest.NewRecorder()
+			lb.ServeHTTP(w, httptest.NewRequest(tc.method, "/orders", nil))
+
+			if w.Code != http.StatusBadGateway {
+				t.Errorf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+			}
+
+			if failing.IsAlive() {
+				t.Error("expected the failing backend to be marked down without a retry")
+			}
+		})
+	}
+}
+
+func TestUnderRoute(t *testing.T) {
+	testCases := []struct {
+		path     string
+		prefix   string
+		expected bool
+	}{
+		{path: "/api/search", prefix: "/api/search", expected: true},
+		{path: "/api/search/", prefix: "/api/search", expected: true},
+		{path: "/api/search/products", prefix: "/api/search", expected: true},
+		{path: "/api/searchlog/delete", prefix: "/api/search", expected: false},
+		{path: "/api/search/products", prefix: "/api/search/", expected: true},
+		{path: "/api/search", prefix: "/api/search/", expected: false},
+		{path: "/orders", prefix: "/", expected: true},
+		{path: "/api", prefix: "/api/search", expected: false},
+		{path: "/orders", prefix: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path+" under "+tc.prefix, func(t *testing.T) {
+			if got := underRoute(tc.path, tc.prefix); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
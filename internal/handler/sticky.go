@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const StickyCookieName = "lb_backend"
+
+// WithStickySessions pins clients to the backend that served their first
+// request using a cookie signed with secret.
+func WithStickySessions(secret []byte) Option {
+	return func(lb *LoadBalancer) {
+		lb.stickySecret = secret
+	}
+}
+
+func (lb *LoadBalancer) stickyEnabled() bool {
+	return len(lb.stickySecret) > 0
+}
+
+// pinnedPeer returns the backend named by a valid sticky cookie, provided it
+// is still in the pool and alive.
+func (lb *LoadBalancer) pinnedPeer(r *http.Request) *backend.Backend {
+	c, err := r.Cookie(StickyCookieName)
+	if err != nil {
+		return nil
+	}
+
+	id, ok := lb.verifySticky(c.Value)
+	if !ok {
+		return nil
+	}
+
+	peer := lb.pool.GetBackendByID(id)
+	if peer == nil || !peer.IsAvailable() {
+		return nil
+	}
+	return peer
+}
+
+// pinOnResponse returns a writer that sets the sticky cookie for peer once a
+// response header is written. A retry replaces the pending peer, and Error
+// drops it, so a failed request never pins the client to the backend that
+// failed it.
+func (lb *LoadBalancer) pinOnResponse(w http.ResponseWriter, peer *backend.Backend) http.ResponseWriter {
+	if sw, ok := w.(*stickyWriter); ok {
+		sw.peer = peer
+		return sw
+	}
+	return &stickyWriter{ResponseWriter: w, lb: lb, peer: peer}
+}
+
+// Error replies like http.Error, dropping any pending sticky cookie.
+func Error(w http.ResponseWriter, error string, code int) {
+	if sw, ok := w.(*stickyWriter); ok {
+		sw.peer = nil
+	}
+	http.Error(w, error, code)
+}
+
+type stickyWriter struct {
+	http.ResponseWriter
+	lb          *LoadBalancer
+	peer        *backend.Backend
+	wroteHeader bool
+}
+
+func (w *stickyWriter) WriteHeader(code int) {
+	// Informational responses are followed by the real one.
+	if !w.wroteHeader && code >= http.StatusOK {
+		w.wroteHeader = true
+		if w.peer != nil {
+			w.lb.setStickyCookie(w.ResponseWriter, w.peer)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *stickyWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *stickyWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (lb *LoadBalancer) setStickyCookie(w http.ResponseWriter, peer *backend.Backend) {
+	// A retry may already have pinned a different backend on this response.
+	h := w.Header()
+	cookies := h.Values("Set-Cookie")
+	h.Del("Set-Cookie")
+	for _, c := range cookies {
+		if !strings.HasPrefix(c, StickyCookieName+"=") {
+			h.Add("Set-Cookie", c)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     StickyCookieName,
+		Value:    lb.signSticky(peer.ID()),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (lb *LoadBalancer) signSticky(id string) string {
+	mac := hmac.New(sha256.New, lb.stickySecret)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (lb *LoadBalancer) verifySticky(value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(value), []byte(lb.signSticky(id)))
+}
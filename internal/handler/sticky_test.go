@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/testutil"
+)
+
+var testSecret = []byte("test-secret")
+
+func stickyCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == StickyCookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSticky_IssuesCookieOnFirstResponse(t *testing.T) {
+	server := testutil.CreateTestServer("backend1", http.StatusOK)
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	lb := New(createTestPool(b), WithStickySessions(testSecret))
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	c := stickyCookie(t, w)
+	if c == nil {
+		t.Fatal("expected sticky cookie to be set")
+	}
+
+	if id, ok := lb.verifySticky(c.Value); !ok || id != b.ID() {
+		t.Errorf("expected cookie to name backend %s, got %q", b.ID(), c.Value)
+	}
+}
+
+func TestSticky_HonorsCookie(t *testing.T) {
+	server1 := testutil.CreateTestServer("backend1", http.StatusOK)
+	defer server1.Close()
+	server2 := testutil.CreateTestServer("backend2", http.StatusOK)
+	defer server2.Close()
+
+	b1 := createTestBackend(server1.URL)
+	b2 := createTestBackend(server2.URL)
+	lb := New(createTestPool(b1, b2), WithStickySessions(testSecret))
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	c := stickyCookie(t, w)
+	first := w.Body.String()
+
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(c)
+		w := httptest.NewRecorder()
+		lb.ServeHTTP(w, req)
+
+		if w.Body.String() != first {
+			t.Fatalf("expected pinned response %q, got %q", first, w.Body.String())
+		}
+		if stickyCookie(t, w) != nil {
+			t.Fatal("expected no new cookie while the pinned backend is healthy")
+		}
+	}
+}
+
+func TestSticky_ReissuesWhenPinnedBackendDead(t *testing.T) {
+	server1 := testutil.CreateTestServer("backend1", http.StatusOK)
+	defer server1.Close()
+	server2 := testutil.CreateTestServer("backend2", http.StatusOK)
+	defer server2.Close()
+
+	b1 := createTestBackend(server1.URL)
+	b2 := createTestBackend(server2.URL)
+	lb := New(createTestPool(b1, b2), WithStickySessions(testSecret))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: StickyCookieName, Value: lb.signSticky(b1.ID())})
+	b1.SetAlive(false)
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if w.Body.String() != "backend2" {
+		t.Errorf("expected fallback to backend2, got %q", w.Body.String())
+	}
+
+	c := stickyCookie(t, w)
+	if c == nil {
+		t.Fatal("expected cookie to be re-issued")
+	}
+	if id, _ := lb.verifySticky(c.Value); id != b2.ID() {
+		t.Error("expected re-issued cookie to pin backend2")
+	}
+}
+
+func TestSticky_ReissuesWhenPinnedBackendRemoved(t *testing.T) {
+	server := testutil.CreateTestServer("backend1", http.StatusOK)
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	lb := New(createTestPool(b), WithStickySessions(testSecret))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: StickyCookieName, Value: lb.signSticky("0123456789abcdef")})
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if stickyCookie(t, w) == nil {
+		t.Error("expected cookie to be re-issued")
+	}
+}
+
+func TestSticky_RejectsTamperedCookie(t *testing.T) {
+	lb := New(createTestPool(), WithStickySessions(testSecret))
+	other := New(createTestPool(), WithStickySessions([]byte("other-secret")))
+
+	testCases := []struct {
+		name  string
+		value string
+	}{
+		{name: "no signature", value: "0123456789abcdef"},
+		{name: "bad signature", value: "0123456789abcdef.AAAA"},
+		{name: "other secret", value: other.signSticky("0123456789abcdef")},
+		{name: "empty id", value: "." + lb.signSticky("")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := lb.verifySticky(tc.value); ok {
+				t.Errorf("expected %q to be rejected", tc.value)
+			}
+		})
+	}
+}
+
+func TestSticky_DisabledByDefault(t *testing.T) {
+	server := testutil.CreateTestServer("ok", http.StatusOK)
+	defer server.Close()
+
+	lb := New(createTestPool(createTestBackend(server.URL)))
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if stickyCookie(t, w) != nil {
+		t.Error("expected no sticky cookie when sticky sessions are disabled")
+	}
+}
+
+func TestSticky_ReplacesCookieOnRetry(t *testing.T) {
+	lb := New(createTestPool(), WithStickySessions(testSecret))
+	b1 := createTestBackend("http://localhost:8080")
+	b2 := createTestBackend("http://localhost:8081")
+
+	w := httptest.NewRecorder()
+	w.Header().Add("Set-Cookie", "other=1")
+	lb.setStickyCookie(w, b1)
+	lb.setStickyCookie(w, b2)
+
+	cookies := w.Header().Values("Set-Cookie")
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies, got %v", cookies)
+	}
+	if id, _ := lb.verifySticky(stickyCookie(t, w).Value); id != b2.ID() {
+		t.Error("expected the latest backend to be pinned")
+	}
+}
+
+func TestSticky_NoCookieWhenRequestFails(t *testing.T) {
+	b := createTestBackend("http://localhost:99999")
+	lb := New(createTestPool(b), WithStickySessions(testSecret))
+	b.ReverseProxy.ErrorHandler = lb.CreateErrorHandler()
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("POST", "/orders", nil))
+
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected status %d, got %d", http.StatusBadGateway, w.Code)
+	}
+	if stickyCookie(t, w) != nil {
+		t.Error("expected no sticky cookie pinning the failed backend")
+	}
+}
+
+func TestSticky_PinsBackendThatServedRetry(t *testing.T) {
+	server := testutil.CreateTestServer("backend1", http.StatusOK)
+	defer server.Close()
+
+	failing := createTestBackend("http://localhost:99999")
+	healthy := createTestBackend(server.URL)
+	lb := New(createTestPool(healthy, failing), WithStickySessions(testSecret))
+	failing.ReverseProxy.ErrorHandler = lb.CreateErrorHandler()
+
+	w := httptest.NewRecorder()
+	lb.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(w.Header().Values("Set-Cookie")) != 1 {
+		t.Fatalf("expected a single cookie, got %v", w.Header().Values("Set-Cookie"))
+	}
+	if id, _ := lb.verifySticky(stickyCookie(t, w).Value); id != healthy.ID() {
+		t.Errorf("expected cookie to pin %s, got %s", healthy.ID(), id)
+	}
+}
@@ -0,0 +1,133 @@
+package healthcheck
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const (
+	// DefaultWorkers bounds how many probes run at once.
+	DefaultWorkers = 10
+	// DefaultJitter is the share of the interval each probe may be delayed by.
+	DefaultJitter = 0.2
+)
+
+// Checker periodically probes a set of backends concurrently.
+type Checker struct {
+	backends func() []*backend.Backend
+	interval time.Duration
+	workers  int
+	jitter   float64
+}
+
+type CheckerOption func(*Checker)
+
+// WithWorkers caps the number of probes in flight.
+func WithWorkers(n int) CheckerOption {
+	return func(c *Checker) {
+		if n > 0 {
+			c.workers = n
+		}
+	}
+}
+
+// WithJitter delays each probe by a random share of the interval, up to
+// fraction, so backends are not all hit at the same instant.
+func WithJitter(fraction float64) CheckerOption {
+	return func(c *Checker) {
+		if fraction >= 0 && fraction < 1 {
+			c.jitter = fraction
+		}
+	}
+}
+
+// NewChecker returns a Checker probing whatever backends returns on each
+// cycle, so backends added later are picked up.
+func NewChecker(backends func() []*backend.Backend, interval time.Duration, opts ...CheckerOption) *Checker {
+	c := &Checker{
+		backends: backends,
+		interval: interval,
+		workers:  DefaultWorkers,
+		jitter:   DefaultJitter,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run checks every backend straight away and then once per interval until ctx
+// is cancelled.
+func (c *Checker) Run(ctx context.Context) {
+	log.Println("Starting health check...")
+	checkBackends(ctx, c.backends(), c.workers, 0)
+	log.Println("Health check completed")
+
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			log.Println("Starting health check...")
+			checkBackends(ctx, c.backends(), c.workers, time.Duration(c.jitter*float64(c.interval)))
+			log.Println("Health check completed")
+		}
+	}
+}
+
+// checkBackends probes backends with at most workers in flight, delaying each
+// one by a random amount below jitter, and returns once all are done.
+func checkBackends(ctx context.Context, backends []*backend.Backend, workers int, jitter time.Duration) {
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+
+	for _, b := range backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if jitter > 0 {
+				t := time.NewTimer(rand.N(jitter))
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return
+				case <-t.C:
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+			defer func() { <-sem }()
+
+			checkBackend(ctx, b)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func checkBackend(ctx context.Context, b *backend.Backend) {
+	err := proberFor(b).Probe(ctx, b)
+	if ctx.Err() != nil {
+		// Shutting down; a cancelled probe says nothing about the backend.
+		return
+	}
+	if err != nil {
+		log.Printf("%s health check failed: %s\n", b.URL, err)
+	}
+
+	b.RecordProbe(err)
+	log.Printf("%s [%s]\n", b.URL, stateOf(b.IsAlive()))
+}
@@ -0,0 +1,176 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// slowProber fails every probe after delay, tracking peak concurrency.
+type slowProber struct {
+	delay   time.Duration
+	active  atomic.Int32
+	peak    atomic.Int32
+	calls   atomic.Int32
+	started chan struct{}
+}
+
+func (p *slowProber) Probe(ctx context.Context, b *backend.Backend) error {
+	p.calls.Add(1)
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	if p.started != nil {
+		select {
+		case p.started <- struct{}{}:
+		default:
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.delay):
+		return errors.New("unreachable")
+	}
+}
+
+func createProbedBackends(n int, p backend.Prober) []*backend.Backend {
+	backends := make([]*backend.Backend, n)
+	for i := range backends {
+		backends[i] = createTestBackend(fmt.Sprintf("http://backend-%d:8080", i))
+		backends[i].Prober = p
+	}
+	return backends
+}
+
+func TestCheckBackends_RunsConcurrently(t *testing.T) {
+	p := &slowProber{delay: 50 * time.Millisecond}
+	backends := createProbedBackends(20, p)
+
+	start := time.Now()
+	checkBackends(context.Background(), backends, 20, 0)
+	elapsed := time.Since(start)
+
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("expected probes to overlap, cycle took %v", elapsed)
+	}
+
+	for _, b := range backends {
+		if b.IsAlive() {
+			t.Errorf("expected %s to be marked down", b.URL)
+		}
+	}
+}
+
+func TestCheckBackends_BoundedWorkers(t *testing.T) {
+	p := &slowProber{delay: 20 * time.Millisecond}
+	backends := createProbedBackends(12, p)
+
+	checkBackends(context.Background(), backends, 3, 0)
+
+	if peak := p.peak.Load(); peak > 3 {
+		t.Errorf("expected at most 3 probes in flight, saw %d", peak)
+	}
+
+	if calls := p.calls.Load(); calls != 12 {
+		t.Errorf("expected every backend to be probed, got %d probes", calls)
+	}
+}
+
+func TestCheckBackends_Jitter(t *testing.T) {
+	p := &slowProber{}
+	backends := createProbedBackends(5, p)
+
+	start := time.Now()
+	checkBackends(context.Background(), backends, 5, 30*time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected jitter to stay below its bound, cycle took %v", elapsed)
+	}
+
+	if calls := p.calls.Load(); calls != 5 {
+		t.Errorf("expected every backend to be probed, got %d probes", calls)
+	}
+}
+
+func TestChecker_ChecksImmediately(t *testing.T) {
+	p := &slowProber{started: make(chan struct{}, 1)}
+	backends := createProbedBackends(1, p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewChecker(func() []*backend.Backend { return backends }, time.Hour).Run(ctx)
+
+	select {
+	case <-p.started:
+	case <-time.After(time.Second):
+		t.Fatal("expected a probe before the first interval elapsed")
+	}
+}
+
+func TestChecker_StopsOnCancel(t *testing.T) {
+	p := &slowProber{delay: time.Hour}
+	backends := createProbedBackends(1, p)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewChecker(func() []*backend.Backend { return backends }, 10*time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return after cancellation")
+	}
+
+	if !backends[0].IsAlive() {
+		t.Error("expected a cancelled probe not to mark the backend down")
+	}
+}
+
+func TestChecker_PicksUpNewBackends(t *testing.T) {
+	p := &slowProber{}
+	var mu sync.Mutex
+	backends := createProbedBackends(1, p)
+	source := func() []*backend.Backend {
+		mu.Lock()
+		defer mu.Unlock()
+		return backends
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewChecker(source, 10*time.Millisecond, WithJitter(0)).Run(ctx)
+
+	added := createTestBackend("http://added:8080")
+	added.Prober = p
+	mu.Lock()
+	backends = append(backends, added)
+	mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for added.IsAlive() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if added.IsAlive() {
+		t.Error("expected a backend added after start to be probed")
+	}
+}
@@ -0,0 +1,109 @@
+package healthcheck
+
+import (
+	"sync"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+type State string
+
+const (
+	StateUp   State = "up"
+	StateDown State = "down"
+)
+
+func stateOf(alive bool) State {
+	if alive {
+		return StateUp
+	}
+	return StateDown
+}
+
+// Event reports a backend moving between up and down.
+type Event struct {
+	Backend  *backend.Backend `json:"-"`
+	URL      string           `json:"backend"`
+	OldState State            `json:"old_state"`
+	NewState State            `json:"new_state"`
+	Reason   string           `json:"reason"`
+	Time     time.Time        `json:"timestamp"`
+}
+
+func NewEvent(b *backend.Backend, alive bool, reason string) Event {
+	return Event{
+		Backend:  b,
+		URL:      b.URL.String(),
+		OldState: stateOf(!alive),
+		NewState: stateOf(alive),
+		Reason:   reason,
+		Time:     time.Now(),
+	}
+}
+
+// Bus fans health events out to in-process subscribers. Subscribers run on
+// the publishing goroutine and must not block; a nil Bus drops events.
+type Bus struct {
+	mux         sync.RWMutex
+	subscribers map[int]func(Event)
+	nextID      int
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]func(Event))}
+}
+
+// Subscribe registers fn for every later event. The returned function
+// unregisters it.
+func (b *Bus) Subscribe(fn func(Event)) func() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = fn
+
+	return func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Watch publishes an Event whenever be goes up or down, however the change
+// was made, until the returned function is called. Pass it to
+// pool.WithWatcher to follow every backend in a pool.
+func (b *Bus) Watch(be *backend.Backend) func() {
+	var mux sync.Mutex
+	last := be.IsAlive()
+	return be.OnStateChange(func(be *backend.Backend) {
+		mux.Lock()
+		alive, reason := be.AliveReason()
+		changed := alive != last
+		last = alive
+		mux.Unlock()
+
+		if changed {
+			if reason == "" {
+				reason = "marked " + string(stateOf(alive))
+			}
+			b.Publish(NewEvent(be, alive, reason))
+		}
+	})
+}
+
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mux.RLock()
+	subscribers := make([]func(Event), 0, len(b.subscribers))
+	for _, fn := range b.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	b.mux.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
@@ -0,0 +1,146 @@
+package healthcheck
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/testutil"
+)
+
+// recordEvents subscribes to bus and returns a function listing what it saw.
+func recordEvents(bus *Bus) func() []Event {
+	var mu sync.Mutex
+	var events []Event
+	bus.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	return func() []Event {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Event(nil), events...)
+	}
+}
+
+func TestBus_PublishAndUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	b := createTestBackend("http://localhost:8080")
+
+	first := 0
+	cancel := bus.Subscribe(func(Event) { first++ })
+	second := 0
+	bus.Subscribe(func(Event) { second++ })
+
+	bus.Publish(NewEvent(b, false, "test"))
+	cancel()
+	bus.Publish(NewEvent(b, true, "test"))
+
+	if first != 1 || second != 2 {
+		t.Errorf("expected 1 and 2 deliveries, got %d and %d", first, second)
+	}
+}
+
+func TestBus_NilDropsEvents(t *testing.T) {
+	var bus *Bus
+	bus.Publish(NewEvent(createTestBackend("http://localhost:8080"), true, "test"))
+}
+
+func TestNewEvent(t *testing.T) {
+	b := createTestBackend("http://localhost:8080")
+	e := NewEvent(b, false, "connection refused")
+
+	if e.Backend != b || e.URL != "http://localhost:8080" {
+		t.Errorf("unexpected backend in %+v", e)
+	}
+
+	if e.OldState != StateUp || e.NewState != StateDown {
+		t.Errorf("expected up -> down, got %s -> %s", e.OldState, e.NewState)
+	}
+
+	if e.Reason != "connection refused" || e.Time.IsZero() {
+		t.Errorf("unexpected reason or time in %+v", e)
+	}
+}
+
+func TestCheckBackends_PublishesTransitions(t *testing.T) {
+	server := testutil.CreateTestServerSimple()
+	b := createTestBackend(server.URL)
+	bus := NewBus()
+	bus.Watch(b)
+	events := recordEvents(bus)
+	backends := []*backend.Backend{b}
+
+	checkBackends(context.Background(), backends, 1, 0)
+	if len(events()) != 0 {
+		t.Fatalf("expected no event while the backend stays up, got %v", events())
+	}
+
+	server.Close()
+	checkBackends(context.Background(), backends, 1, 0)
+	checkBackends(context.Background(), backends, 1, 0)
+
+	got := events()
+	if len(got) != 1 {
+		t.Fatalf("expected exactly one event, got %d", len(got))
+	}
+
+	if got[0].NewState != StateDown || !strings.HasPrefix(got[0].Reason, "health check failed") {
+		t.Errorf("expected down event with the probe error, got %+v", got[0])
+	}
+}
+
+func TestCheckBackends_PublishesRecovery(t *testing.T) {
+	server := testutil.CreateTestServerSimple()
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	b.Rise = 2
+	b.SetAlive(false)
+	bus := NewBus()
+	bus.Watch(b)
+	events := recordEvents(bus)
+	backends := []*backend.Backend{b}
+
+	checkBackends(context.Background(), backends, 1, 0)
+	if len(events()) != 0 {
+		t.Fatal("expected no event before the rise threshold")
+	}
+
+	checkBackends(context.Background(), backends, 1, 0)
+
+	got := events()
+	if len(got) != 1 || got[0].OldState != StateDown || got[0].NewState != StateUp {
+		t.Errorf("expected one down -> up event, got %+v", got)
+	}
+}
+
+func TestBus_WatchPublishesEveryTransition(t *testing.T) {
+	b := createTestBackend("http://localhost:8080")
+	bus := NewBus()
+	events := recordEvents(bus)
+	cancel := bus.Watch(b)
+
+	b.SetDraining(true)
+	b.MarkDown("proxy retries exhausted")
+	b.SetAlive(false)
+	b.SetAlive(true)
+	cancel()
+	b.SetAlive(false)
+
+	got := events()
+	if len(got) != 2 {
+		t.Fatalf("expected a down and an up event, got %+v", got)
+	}
+
+	if got[0].NewState != StateDown || got[0].Reason != "proxy retries exhausted" {
+		t.Errorf("expected down event with its reason, got %+v", got[0])
+	}
+
+	if got[1].NewState != StateUp || got[1].Reason != "marked up" {
+		t.Errorf("expected up event, got %+v", got[1])
+	}
+}
@@ -0,0 +1,234 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// grpcHealthPath is the grpc.health.v1.Health/Check method.
+const grpcHealthPath = "/grpc.health.v1.Health/Check"
+
+// maxGRPCMessage caps the health response read from a backend.
+const maxGRPCMessage = 4 << 10
+
+// HealthCheckResponse.ServingStatus values from grpc/health/v1/health.proto.
+const (
+	grpcUnknown        = 0
+	grpcServing        = 1
+	grpcNotServing     = 2
+	grpcServiceUnknown = 3
+)
+
+var grpcStatusNames = map[uint64]string{
+	grpcUnknown:        "UNKNOWN",
+	grpcServing:        "SERVING",
+	grpcNotServing:     "NOT_SERVING",
+	grpcServiceUnknown: "SERVICE_UNKNOWN",
+}
+
+// grpcClient speaks HTTP/2 only: cleartext (h2c) for http backends and
+// negotiated over TLS for https ones.
+var grpcClient = func() *http.Client {
+	var protocols http.Protocols
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+}()
+
+// GRPCProber calls the standard grpc.health.v1.Health/Check RPC and considers
+// the backend up only when it reports SERVING. An empty Service asks about
+// the server as a whole.
+type GRPCProber struct {
+	Service string
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+func (p *GRPCProber) Probe(ctx context.Context, b *backend.Backend) error {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(p.Timeout))
+	defer cancel()
+
+	target := &url.URL{
+		Scheme: b.URL.Scheme,
+		Host:   probeAddr(b),
+		Path:   grpcHealthPath,
+	}
+	body := encodeGRPCFrame(encodeHealthCheckRequest(p.Service))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	client := p.Client
+	if client == nil {
+		client = grpcClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	msg, readErr := readGRPCFrame(resp.Body)
+	// Drain to EOF so trailers are available.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxGRPCMessage))
+
+	if err := grpcStatus(resp); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return readErr
+	}
+
+	status, err := decodeHealthCheckResponse(msg)
+	if err != nil {
+		return err
+	}
+	if status != grpcServing {
+		name, ok := grpcStatusNames[status]
+		if !ok {
+			name = fmt.Sprintf("status %d", status)
+		}
+		return fmt.Errorf("grpc health status %s", name)
+	}
+	return nil
+}
+
+// grpcStatus checks grpc-status, sent as a trailer or, for responses without
+// a body, as a header.
+func grpcStatus(resp *http.Response) error {
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		return errors.New("missing grpc-status")
+	}
+	if status != "0" {
+		if message != "" {
+			return fmt.Errorf("grpc status %s: %s", status, message)
+		}
+		return fmt.Errorf("grpc status %s", status)
+	}
+	return nil
+}
+
+// encodeGRPCFrame prefixes msg with the uncompressed length-prefixed message
+// header.
+func encodeGRPCFrame(msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+func readGRPCFrame(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("reading grpc message: %w", err)
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed grpc messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > maxGRPCMessage {
+		return nil, fmt.Errorf("grpc message of %d bytes is too large", n)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("reading grpc message: %w", err)
+	}
+	return msg, nil
+}
+
+// encodeHealthCheckRequest encodes HealthCheckRequest{service = 1}.
+func encodeHealthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	msg := []byte{0x0a}
+	msg = binary.AppendUvarint(msg, uint64(len(service)))
+	return append(msg, service...)
+}
+
+// decodeHealthCheckResponse returns HealthCheckResponse.status (field 1),
+// skipping any fields it does not know.
+func decodeHealthCheckResponse(msg []byte) (uint64, error) {
+	var status uint64
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, errors.New("malformed grpc health response")
+		}
+		msg = msg[n:]
+
+		field, wireType := key>>3, key&7
+		switch wireType {
+		case 0:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[n:]
+			if field == 1 {
+				status = v
+			}
+		case 1:
+			if len(msg) < 8 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[8:]
+		case 2:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[n+int(l):]
+		case 5:
+			if len(msg) < 4 {
+				return 0, errors.New("malformed grpc health response")
+			}
+			msg = msg[4:]
+		default:
+			return 0, fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+	}
+	return status, nil
+}
+
+func parseGRPCProber(opts map[string]string) (*GRPCProber, error) {
+	p := &GRPCProber{Timeout: DefaultTimeout}
+
+	for k, v := range opts {
+		var err error
+		switch k {
+		case "service":
+			p.Service = v
+		case "timeout":
+			p.Timeout, err = parseTimeout(v)
+		default:
+			return nil, fmt.Errorf("unknown grpc health check option %q", k)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
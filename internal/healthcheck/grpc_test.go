@@ -0,0 +1,225 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createGRPCHealthServer starts an in-process h2c server implementing
+// grpc.health.v1.Health/Check with the given per-service statuses; "" is the
+// overall server status. Frames are parsed and built byte by byte rather than
+// with the prober's helpers, so the tests pin the wire format.
+func createGRPCHealthServer(t *testing.T, statuses map[string]byte) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.URL.Path != "/grpc.health.v1.Health/Check" ||
+			!strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+		body, _ := io.ReadAll(r.Body)
+		service, ok := parseHealthCheckFrame(body)
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+			w.Header().Set("Grpc-Status", "13")
+			return
+		}
+
+		status, ok := statuses[service]
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+
+		// Uncompressed frame of a 2 byte HealthCheckResponse{status = 1}.
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte{0, 0, 0, 0, 2, 0x08, status})
+		w.Header().Set("Grpc-Status", "0")
+	}))
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	server.Config.Protocols = &protocols
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// parseHealthCheckFrame reads the service from a framed HealthCheckRequest,
+// supporting the short service names the tests use.
+func parseHealthCheckFrame(frame []byte) (string, bool) {
+	if len(frame) < 5 || frame[0] != 0 || frame[1] != 0 || frame[2] != 0 || int(frame[4]) != len(frame)-5 {
+		return "", false
+	}
+	msg := frame[5:]
+	if len(msg) == 0 {
+		return "", true
+	}
+	if len(msg) < 2 || msg[0] != 0x0a || int(msg[1]) != len(msg)-2 {
+		return "", false
+	}
+	return string(msg[2:]), true
+}
+
+func TestGRPCProber_RequestWireFormat(t *testing.T) {
+	testCases := []struct {
+		service  string
+		expected []byte
+	}{
+		{service: "", expected: []byte{0, 0, 0, 0, 0}},
+		{service: "api", expected: []byte{0, 0, 0, 0, 5, 0x0a, 3, 'a', 'p', 'i'}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.service, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received <- body
+				w.Header().Set("Content-Type", "application/grpc")
+				w.Header().Set("Trailer", "Grpc-Status")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte{0, 0, 0, 0, 2, 0x08, 0x01})
+				w.Header().Set("Grpc-Status", "0")
+			}))
+			var protocols http.Protocols
+			protocols.SetUnencryptedHTTP2(true)
+			server.Config.Protocols = &protocols
+			server.Start()
+			defer server.Close()
+
+			b := createTestBackend(server.URL)
+			if err := (&GRPCProber{Service: tc.service}).Probe(context.Background(), b); err != nil {
+				t.Fatalf("expected SERVING backend to pass, got %v", err)
+			}
+
+			if got := <-received; !bytes.Equal(got, tc.expected) {
+				t.Errorf("expected request bytes %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGRPCProber_Serving(t *testing.T) {
+	server := createGRPCHealthServer(t, map[string]byte{"": grpcServing})
+	b := createTestBackend(server.URL)
+	p := &GRPCProber{Timeout: time.Second}
+
+	if err := p.Probe(context.Background(), b); err != nil {
+		t.Errorf("expected SERVING backend to pass, got %v", err)
+	}
+}
+
+func TestGRPCProber_NotServing(t *testing.T) {
+	server := createGRPCHealthServer(t, map[string]byte{"": grpcNotServing})
+	b := createTestBackend(server.URL)
+	p := &GRPCProber{Timeout: time.Second}
+
+	err := p.Probe(context.Background(), b)
+	if err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Errorf("expected NOT_SERVING error, got %v", err)
+	}
+}
+
+func TestGRPCProber_Service(t *testing.T) {
+	server := createGRPCHealthServer(t, map[string]byte{
+		"":              grpcServing,
+		"orders.Orders": grpcNotServing,
+		"users.Users":   grpcServing,
+	})
+	b := createTestBackend(server.URL)
+
+	if err := (&GRPCProber{Service: "users.Users"}).Probe(context.Background(), b); err != nil {
+		t.Errorf("expected serving service to pass, got %v", err)
+	}
+
+	if err := (&GRPCProber{Service: "orders.Orders"}).Probe(context.Background(), b); err == nil {
+		t.Error("expected not serving service to fail")
+	}
+}
+
+func TestGRPCProber_UnknownService(t *testing.T) {
+	server := createGRPCHealthServer(t, map[string]byte{"": grpcServing})
+	b := createTestBackend(server.URL)
+	p := &GRPCProber{Service: "missing.Service"}
+
+	err := p.Probe(context.Background(), b)
+	if err == nil || !strings.Contains(err.Error(), "grpc status 5") {
+		t.Errorf("expected grpc NOT_FOUND status, got %v", err)
+	}
+}
+
+func TestGRPCProber_NotGRPC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	p := &GRPCProber{Timeout: time.Second}
+
+	if err := p.Probe(context.Background(), b); err == nil {
+		t.Error("expected a plain HTTP/1 backend to fail the gRPC probe")
+	}
+}
+
+func TestGRPCProber_Unreachable(t *testing.T) {
+	b := createTestBackend("http://127.0.0.1:1")
+	p := &GRPCProber{Timeout: time.Second}
+
+	if err := p.Probe(context.Background(), b); err == nil {
+		t.Error("expected unreachable backend to fail")
+	}
+}
+
+func TestDecodeHealthCheckResponse_SkipsUnknownFields(t *testing.T) {
+	msg := []byte{0x12, 0x02, 'h', 'i'}
+	msg = append(msg, 0x08, grpcServing)
+	msg = append(msg, 0x1d, 0, 0, 0, 0)
+
+	status, err := decodeHealthCheckResponse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status != grpcServing {
+		t.Errorf("expected SERVING, got %d", status)
+	}
+}
+
+func TestDecodeHealthCheckResponse_Malformed(t *testing.T) {
+	if _, err := decodeHealthCheckResponse([]byte{0x12, 0x05, 'h'}); err == nil {
+		t.Error("expected truncated message to fail")
+	}
+}
+
+func TestParseProber_GRPC(t *testing.T) {
+	p, err := ParseProber(map[string]string{"type": "grpc", "service": "users.Users", "timeout": "1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, ok := p.(*GRPCProber)
+	if !ok {
+		t.Fatalf("expected gRPC prober, got %T", p)
+	}
+
+	if g.Service != "users.Users" || g.Timeout != time.Second {
+		t.Errorf("unexpected prober %+v", g)
+	}
+
+	if _, err := ParseProber(map[string]string{"type": "grpc", "path": "/"}); err == nil {
+		t.Error("expected unknown grpc option to fail")
+	}
+}
@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"log"
 	"net"
 	"net/url"
@@ -10,8 +11,7 @@ import (
 )
 
 func IsBackendAlive(u *url.URL) bool {
-	timeout := 2 * time.Second
-	conn, err := net.DialTimeout("tcp", u.Host, timeout)
+	conn, err := net.DialTimeout("tcp", u.Host, DefaultTimeout)
 	if err != nil {
 		log.Println("Site unreachable, error: ", err)
 		return false
@@ -20,25 +20,13 @@ func IsBackendAlive(u *url.URL) bool {
 	return true
 }
 
+// CheckBackends probes every backend once, DefaultWorkers at a time.
 func CheckBackends(backends []*backend.Backend) {
-	for _, b := range backends {
-		status := "up"
-		alive := IsBackendAlive(b.URL)
-		b.SetAlive(alive)
-		if !alive {
-			status = "down"
-		}
-		log.Printf("%s [%s]\n", b.URL, status)
-	}
+	checkBackends(context.Background(), backends, DefaultWorkers, 0)
 }
 
-func StartHealthCheck(backends []*backend.Backend, interval time.Duration) {
-	t := time.NewTicker(interval)
-	defer t.Stop()
-
-	for range t.C {
-		log.Println("Starting health check...")
-		CheckBackends(backends)
-		log.Println("Health check completed")
-	}
+// StartHealthCheck checks backends immediately and then every interval until
+// ctx is cancelled.
+func StartHealthCheck(ctx context.Context, backends []*backend.Backend, interval time.Duration) {
+	NewChecker(func() []*backend.Backend { return backends }, interval).Run(ctx)
 }
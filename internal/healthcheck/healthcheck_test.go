@@ -1,6 +1,7 @@
 package healthcheck
 
 import (
+	"context"
 	"net"
 	"net/http/httputil"
 	"net/url"
@@ -144,7 +145,9 @@ func TestStartHealthCheck_ExecutesPeriodically(t *testing.T) {
 
 	interval := 50 * time.Millisecond
 
-	go StartHealthCheck(backends, interval)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go StartHealthCheck(ctx, backends, interval)
 
 	time.Sleep(interval * 2)
 
@@ -164,3 +167,44 @@ func TestCheckBackends_EmptyList(t *testing.T) {
 	backends := []*backend.Backend{}
 	CheckBackends(backends)
 }
+
+func TestCheckBackends_FallThreshold(t *testing.T) {
+	b := createTestBackend("http://localhost:99999")
+	b.Fall = 3
+	backends := []*backend.Backend{b}
+
+	CheckBackends(backends)
+	CheckBackends(backends)
+
+	if !b.IsAlive() {
+		t.Fatal("expected backend to survive failures below the fall threshold")
+	}
+
+	CheckBackends(backends)
+
+	if b.IsAlive() {
+		t.Error("expected backend to be marked down after 3 consecutive failures")
+	}
+}
+
+func TestCheckBackends_RiseThreshold(t *testing.T) {
+	server := testutil.CreateTestServerSimple()
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	b.Rise = 2
+	b.SetAlive(false)
+	backends := []*backend.Backend{b}
+
+	CheckBackends(backends)
+
+	if b.IsAlive() {
+		t.Fatal("expected backend to stay down after a single success")
+	}
+
+	CheckBackends(backends)
+
+	if !b.IsAlive() {
+		t.Error("expected backend to come back after 2 consecutive successes")
+	}
+}
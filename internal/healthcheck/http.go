@@ -0,0 +1,177 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// maxBodyMatch caps how much of a health response is read for body matching.
+const maxBodyMatch = 64 << 10
+
+type StatusRange struct {
+	Min, Max int
+}
+
+var DefaultStatuses = []StatusRange{{Min: 200, Max: 399}}
+
+// probeClient reports redirects as-is instead of following them, so a 302 to
+// a login page is judged on its own status.
+var probeClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// HTTPProber considers a backend up when a request to Path answers with an
+// expected status and, if configured, a body containing Body or matching
+// BodyRegex.
+type HTTPProber struct {
+	Method    string
+	Path      string
+	Host      string
+	Statuses  []StatusRange
+	Body      string
+	BodyRegex *regexp.Regexp
+	Timeout   time.Duration
+	Client    *http.Client
+}
+
+func (p *HTTPProber) Probe(ctx context.Context, b *backend.Backend) error {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(p.Timeout))
+	defer cancel()
+
+	target := &url.URL{
+		Scheme: b.URL.Scheme,
+		Host:   probeAddr(b),
+		Path:   p.Path,
+	}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+	if path, query, ok := strings.Cut(target.Path, "?"); ok {
+		target.Path = path
+		target.RawQuery = query
+	}
+
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	if p.Host != "" {
+		req.Host = p.Host
+	}
+
+	client := p.Client
+	if client == nil {
+		client = probeClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !statusAllowed(resp.StatusCode, p.Statuses) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if p.Body == "" && p.BodyRegex == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyMatch))
+	if err != nil {
+		return err
+	}
+	if p.Body != "" && !strings.Contains(string(body), p.Body) {
+		return fmt.Errorf("body does not contain %q", p.Body)
+	}
+	if p.BodyRegex != nil && !p.BodyRegex.Match(body) {
+		return fmt.Errorf("body does not match %q", p.BodyRegex)
+	}
+	return nil
+}
+
+func statusAllowed(code int, ranges []StatusRange) bool {
+	if len(ranges) == 0 {
+		ranges = DefaultStatuses
+	}
+	for _, r := range ranges {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatusRanges parses expected statuses such as "200", "200-299" or
+// "200-299;301", using ';' because ',' separates backends.
+func ParseStatusRanges(s string) ([]StatusRange, error) {
+	var ranges []StatusRange
+	for part := range strings.SplitSeq(s, ";") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			hi = lo
+		}
+		min, err1 := strconv.Atoi(lo)
+		max, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || min < 100 || max > 599 || min > max {
+			return nil, fmt.Errorf("invalid status range %q", part)
+		}
+		ranges = append(ranges, StatusRange{Min: min, Max: max})
+	}
+	return ranges, nil
+}
+
+func parseHTTPProber(opts map[string]string) (*HTTPProber, error) {
+	p := &HTTPProber{
+		Method:   http.MethodGet,
+		Path:     "/",
+		Statuses: DefaultStatuses,
+		Timeout:  DefaultTimeout,
+	}
+
+	for k, v := range opts {
+		var err error
+		switch k {
+		case "method":
+			p.Method = strings.ToUpper(v)
+		case "path":
+			if !strings.HasPrefix(v, "/") {
+				return nil, fmt.Errorf("health check path must start with '/', got %q", v)
+			}
+			p.Path = v
+		case "host":
+			p.Host = v
+		case "status":
+			p.Statuses, err = ParseStatusRanges(v)
+		case "body":
+			p.Body = v
+		case "body-regex":
+			p.BodyRegex, err = regexp.Compile(v)
+		case "timeout":
+			p.Timeout, err = parseTimeout(v)
+		default:
+			return nil, fmt.Errorf("unknown http health check option %q", k)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
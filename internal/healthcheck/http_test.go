@@ -0,0 +1,151 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+	"github.com/eltoncampos/load-balancer/testutil"
+)
+
+func TestHTTPProber_Status(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		ranges   []StatusRange
+		expectOK bool
+	}{
+		{name: "200 default", status: http.StatusOK, expectOK: true},
+		{name: "500 default", status: http.StatusInternalServerError, expectOK: false},
+		{name: "302 default", status: http.StatusFound, expectOK: true},
+		{name: "204 in range", status: http.StatusNoContent, ranges: []StatusRange{{200, 204}}, expectOK: true},
+		{name: "302 outside range", status: http.StatusFound, ranges: []StatusRange{{200, 299}}, expectOK: false},
+		{name: "503 explicitly allowed", status: http.StatusServiceUnavailable, ranges: []StatusRange{{200, 299}, {503, 503}}, expectOK: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := testutil.CreateTestServer("", tc.status)
+			defer server.Close()
+
+			p := &HTTPProber{Statuses: tc.ranges}
+			err := p.Probe(context.Background(), createTestBackend(server.URL))
+
+			if (err == nil) != tc.expectOK {
+				t.Errorf("expected ok=%v, got err=%v", tc.expectOK, err)
+			}
+		})
+	}
+}
+
+func TestHTTPProber_Request(t *testing.T) {
+	var method, path, query, host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, query, host = r.Method, r.URL.Path, r.URL.RawQuery, r.Host
+	}))
+	defer server.Close()
+
+	p := &HTTPProber{Method: http.MethodHead, Path: "/healthz?deep=1", Host: "app.internal"}
+	if err := p.Probe(context.Background(), createTestBackend(server.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodHead || path != "/healthz" || query != "deep=1" || host != "app.internal" {
+		t.Errorf("unexpected request %s %s?%s Host=%s", method, path, query, host)
+	}
+}
+
+func TestHTTPProber_Body(t *testing.T) {
+	server := testutil.CreateTestServer(`{"status":"UP","db":"ok"}`, http.StatusOK)
+	defer server.Close()
+	b := createTestBackend(server.URL)
+
+	testCases := []struct {
+		name     string
+		prober   *HTTPProber
+		expectOK bool
+	}{
+		{name: "substring match", prober: &HTTPProber{Body: `"status":"UP"`}, expectOK: true},
+		{name: "substring mismatch", prober: &HTTPProber{Body: "DOWN"}, expectOK: false},
+		{name: "regex match", prober: &HTTPProber{BodyRegex: regexp.MustCompile(`"db":"(ok|degraded)"`)}, expectOK: true},
+		{name: "regex mismatch", prober: &HTTPProber{BodyRegex: regexp.MustCompile(`^DOWN`)}, expectOK: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.prober.Probe(context.Background(), b)
+			if (err == nil) != tc.expectOK {
+				t.Errorf("expected ok=%v, got err=%v", tc.expectOK, err)
+			}
+		})
+	}
+}
+
+func TestHTTPProber_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	p := &HTTPProber{Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	err := p.Probe(context.Background(), createTestBackend(server.URL))
+
+	if err == nil {
+		t.Error("expected probe to time out")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("expected probe to give up after its timeout")
+	}
+}
+
+func TestHTTPProber_Unreachable(t *testing.T) {
+	server := testutil.CreateTestServerSimple()
+	b := createTestBackend(server.URL)
+	server.Close()
+
+	if err := (&HTTPProber{}).Probe(context.Background(), b); err == nil {
+		t.Error("expected probe to fail for a stopped server")
+	}
+}
+
+func TestCheckBackends_UsesHTTPProber(t *testing.T) {
+	server := testutil.CreateTestServer("error", http.StatusInternalServerError)
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	tcpOnly := createTestBackend(server.URL)
+	b.Prober = &HTTPProber{}
+
+	CheckBackends([]*backend.Backend{b, tcpOnly})
+
+	if b.IsAlive() {
+		t.Error("expected backend answering 500 to be marked down by the HTTP check")
+	}
+	if !tcpOnly.IsAlive() {
+		t.Error("expected backend without HTTP check to pass the TCP check")
+	}
+}
+
+func TestHTTPProber_DoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer server.Close()
+
+	p := &HTTPProber{Path: "/healthz", Statuses: []StatusRange{{200, 299}}}
+	if err := p.Probe(context.Background(), createTestBackend(server.URL)); err == nil {
+		t.Error("expected redirect to be judged on its own status")
+	}
+}
@@ -0,0 +1,70 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const DefaultTimeout = 2 * time.Second
+
+type Prober = backend.Prober
+
+func proberFor(b *backend.Backend) Prober {
+	if b.Prober != nil {
+		return b.Prober
+	}
+	return &TCPProber{Timeout: DefaultTimeout}
+}
+
+// probeAddr is the host:port probes connect to: b.HealthAddr when set, with a
+// missing host taken from the backend URL, otherwise the URL's own host.
+func probeAddr(b *backend.Backend) string {
+	if b.HealthAddr == "" {
+		return b.URL.Host
+	}
+	host, port, err := net.SplitHostPort(b.HealthAddr)
+	if err != nil || host != "" {
+		return b.HealthAddr
+	}
+	return net.JoinHostPort(b.URL.Hostname(), port)
+}
+
+// ParseProber builds a Prober from per-backend options. "type" selects the
+// probe ("tcp", "http" or "grpc"); the remaining keys configure it.
+func ParseProber(opts map[string]string) (Prober, error) {
+	kind := opts["type"]
+	rest := make(map[string]string, len(opts))
+	for k, v := range opts {
+		if k != "type" {
+			rest[k] = v
+		}
+	}
+
+	switch kind {
+	case "", "tcp":
+		return parseTCPProber(rest)
+	case "http":
+		return parseHTTPProber(rest)
+	case "grpc":
+		return parseGRPCProber(rest)
+	}
+	return nil, fmt.Errorf("unknown health check type %q", kind)
+}
+
+func parseTimeout(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid health check timeout %q", v)
+	}
+	return d, nil
+}
+
+func timeoutOrDefault(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultTimeout
+	}
+	return d
+}
@@ -0,0 +1,159 @@
+package healthcheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/testutil"
+)
+
+func TestTCPProber(t *testing.T) {
+	server := testutil.CreateTestServerSimple()
+	b := createTestBackend(server.URL)
+	p := &TCPProber{Timeout: time.Second}
+
+	if err := p.Probe(context.Background(), b); err != nil {
+		t.Errorf("expected probe to succeed, got %v", err)
+	}
+
+	server.Close()
+
+	if err := p.Probe(context.Background(), b); err == nil {
+		t.Error("expected probe to fail once the server is stopped")
+	}
+}
+
+func TestProberFor_DefaultsToTCP(t *testing.T) {
+	b := createTestBackend("http://localhost:8080")
+
+	if _, ok := proberFor(b).(*TCPProber); !ok {
+		t.Errorf("expected TCP prober by default, got %T", proberFor(b))
+	}
+
+	custom := &HTTPProber{}
+	b.Prober = custom
+	if proberFor(b) != custom {
+		t.Error("expected backend prober to be used")
+	}
+}
+
+func TestParseProber(t *testing.T) {
+	p, err := ParseProber(map[string]string{"type": "tcp", "timeout": "5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tcp, ok := p.(*TCPProber); !ok || tcp.Timeout != 5*time.Second {
+		t.Errorf("unexpected prober %#v", p)
+	}
+
+	p, err = ParseProber(map[string]string{
+		"type":       "http",
+		"method":     "head",
+		"path":       "/healthz?full=1",
+		"host":       "internal.example",
+		"status":     "200-204;301",
+		"body":       "ok",
+		"body-regex": "^o.$",
+		"timeout":    "500ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, ok := p.(*HTTPProber)
+	if !ok {
+		t.Fatalf("expected HTTP prober, got %T", p)
+	}
+	if h.Method != "HEAD" || h.Path != "/healthz?full=1" || h.Host != "internal.example" {
+		t.Errorf("unexpected request settings %+v", h)
+	}
+	if len(h.Statuses) != 2 || h.Statuses[0] != (StatusRange{200, 204}) || h.Statuses[1] != (StatusRange{301, 301}) {
+		t.Errorf("unexpected statuses %v", h.Statuses)
+	}
+	if h.Body != "ok" || h.BodyRegex.String() != "^o.$" || h.Timeout != 500*time.Millisecond {
+		t.Errorf("unexpected matchers %+v", h)
+	}
+}
+
+func TestParseProber_Invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		opts map[string]string
+	}{
+		{name: "unknown type", opts: map[string]string{"type": "icmp"}},
+		{name: "http option on tcp", opts: map[string]string{"type": "tcp", "path": "/"}},
+		{name: "bad timeout", opts: map[string]string{"type": "http", "timeout": "soon"}},
+		{name: "relative path", opts: map[string]string{"type": "http", "path": "healthz"}},
+		{name: "bad status", opts: map[string]string{"type": "http", "status": "2xx"}},
+		{name: "inverted range", opts: map[string]string{"type": "http", "status": "299-200"}},
+		{name: "bad regex", opts: map[string]string{"type": "http", "body-regex": "("}},
+		{name: "unknown option", opts: map[string]string{"type": "http", "color": "blue"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseProber(tc.opts); err == nil {
+				t.Errorf("expected error for %v", tc.opts)
+			}
+		})
+	}
+}
+
+func TestProbeAddr(t *testing.T) {
+	testCases := []struct {
+		name       string
+		url        string
+		healthAddr string
+		expected   string
+	}{
+		{name: "no override", url: "http://10.0.0.1:8080", expected: "10.0.0.1:8080"},
+		{name: "full address", url: "http://10.0.0.1:8080", healthAddr: "10.0.0.9:9090", expected: "10.0.0.9:9090"},
+		{name: "port only", url: "http://10.0.0.1:8080", healthAddr: ":9090", expected: "10.0.0.1:9090"},
+		{name: "port only ipv6", url: "http://[::1]:8080", healthAddr: ":9090", expected: "[::1]:9090"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := createTestBackend(tc.url)
+			b.HealthAddr = tc.healthAddr
+
+			if got := probeAddr(b); got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProbers_UseHealthAddr(t *testing.T) {
+	httpServer := testutil.CreateTestServerSimple()
+	defer httpServer.Close()
+	grpcServer := createGRPCHealthServer(t, map[string]byte{"": grpcServing})
+
+	testCases := []struct {
+		name   string
+		prober Prober
+		target string
+	}{
+		{name: "tcp", prober: &TCPProber{Timeout: time.Second}, target: httpServer.URL},
+		{name: "http", prober: &HTTPProber{Timeout: time.Second}, target: httpServer.URL},
+		{name: "grpc", prober: &GRPCProber{Timeout: time.Second}, target: grpcServer.URL},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := createTestBackend("http://127.0.0.1:1")
+
+			if err := tc.prober.Probe(context.Background(), b); err == nil {
+				t.Fatal("expected probe of the traffic address to fail")
+			}
+
+			b.HealthAddr = strings.TrimPrefix(tc.target, "http://")
+
+			if err := tc.prober.Probe(context.Background(), b); err != nil {
+				t.Errorf("expected probe of the health address to pass, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// TCPProber considers a backend up when a TCP connection can be opened. For
+// non-HTTP protocols it can also write Send and wait for a reply containing
+// Expect or matching ExpectRegex, e.g. "PING\r\n" answered by "+PONG".
+type TCPProber struct {
+	Send        []byte
+	Expect      string
+	ExpectRegex *regexp.Regexp
+	// TLS wraps the connection in TLS before anything is sent.
+	TLS     *tls.Config
+	Timeout time.Duration
+}
+
+func (p *TCPProber) Probe(ctx context.Context, b *backend.Backend) error {
+	ctx, cancel := context.WithTimeout(ctx, timeoutOrDefault(p.Timeout))
+	defer cancel()
+
+	var conn net.Conn
+	var err error
+	if p.TLS != nil {
+		d := tls.Dialer{Config: p.TLS}
+		conn, err = d.DialContext(ctx, "tcp", probeAddr(b))
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", probeAddr(b))
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if len(p.Send) == 0 && p.Expect == "" && p.ExpectRegex == nil {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if len(p.Send) > 0 {
+		if _, err := conn.Write(p.Send); err != nil {
+			return err
+		}
+	}
+
+	if p.Expect == "" && p.ExpectRegex == nil {
+		return nil
+	}
+	return p.expect(conn)
+}
+
+// expect reads until the reply matches, the connection ends or maxBodyMatch
+// bytes have arrived.
+func (p *TCPProber) expect(conn net.Conn) error {
+	buf := make([]byte, 0, 512)
+	chunk := make([]byte, 512)
+	for {
+		n, err := conn.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		if p.matches(buf) {
+			return nil
+		}
+		if err != nil && len(buf) == 0 {
+			return fmt.Errorf("no reply: %w", err)
+		}
+		if err != nil || len(buf) >= maxBodyMatch {
+			return fmt.Errorf("unexpected reply %q", truncate(buf, 64))
+		}
+	}
+}
+
+func (p *TCPProber) matches(buf []byte) bool {
+	if p.Expect != "" && !bytes.Contains(buf, []byte(p.Expect)) {
+		return false
+	}
+	if p.ExpectRegex != nil && !p.ExpectRegex.Match(buf) {
+		return false
+	}
+	return true
+}
+
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
+
+func parseTCPProber(opts map[string]string) (*TCPProber, error) {
+	p := &TCPProber{Timeout: DefaultTimeout}
+	var useTLS, skipVerify bool
+	var serverName string
+
+	for k, v := range opts {
+		var err error
+		switch k {
+		case "send":
+			var s string
+			s, err = unescape(v)
+			p.Send = []byte(s)
+		case "expect":
+			p.Expect, err = unescape(v)
+		case "expect-regex":
+			p.ExpectRegex, err = regexp.Compile(v)
+		case "tls":
+			useTLS, err = strconv.ParseBool(v)
+		case "tls-skip-verify":
+			skipVerify, err = strconv.ParseBool(v)
+		case "tls-server-name":
+			serverName = v
+		case "timeout":
+			p.Timeout, err = parseTimeout(v)
+		default:
+			return nil, fmt.Errorf("unknown tcp health check option %q", k)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tcp health check option %s=%q", k, v)
+		}
+	}
+
+	if useTLS {
+		p.TLS = &tls.Config{ServerName: serverName, InsecureSkipVerify: skipVerify}
+	} else if skipVerify || serverName != "" {
+		return nil, errors.New("tcp health check tls options need health-tls=true")
+	}
+	return p, nil
+}
+
+// unescape interprets Go escapes such as \r\n and \x00 so binary payloads fit
+// in a backend option.
+func unescape(v string) (string, error) {
+	return strconv.Unquote(`"` + v + `"`)
+}
@@ -0,0 +1,167 @@
+package healthcheck
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// createLineServer accepts connections and answers each line read with
+// reply(line), or writes greeting first when set, like an SMTP banner.
+func createLineServer(t *testing.T, greeting string, reply func(string) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if greeting != "" {
+					_, _ = conn.Write([]byte(greeting))
+				}
+				if reply == nil {
+					time.Sleep(time.Second)
+					return
+				}
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write([]byte(reply(line)))
+				}
+			}()
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+func redisReply(line string) string {
+	if line == "PING\r\n" {
+		return "+PONG\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestTCPProber_SendExpect(t *testing.T) {
+	b := createTestBackend(createLineServer(t, "", redisReply))
+	p := &TCPProber{Send: []byte("PING\r\n"), Expect: "+PONG", Timeout: time.Second}
+
+	if err := p.Probe(context.Background(), b); err != nil {
+		t.Errorf("expected PONG to pass, got %v", err)
+	}
+}
+
+func TestTCPProber_UnexpectedReply(t *testing.T) {
+	b := createTestBackend(createLineServer(t, "", redisReply))
+	p := &TCPProber{Send: []byte("HELLO\r\n"), Expect: "+PONG", Timeout: 200 * time.Millisecond}
+
+	if err := p.Probe(context.Background(), b); err == nil {
+		t.Error("expected an error reply to fail")
+	}
+}
+
+func TestTCPProber_ExpectBanner(t *testing.T) {
+	b := createTestBackend(createLineServer(t, "220 mail.example.com ESMTP ready\r\n", nil))
+	p := &TCPProber{ExpectRegex: regexp.MustCompile(`^220 `), Timeout: time.Second}
+
+	if err := p.Probe(context.Background(), b); err != nil {
+		t.Errorf("expected banner to match, got %v", err)
+	}
+}
+
+func TestTCPProber_ExpectTimesOut(t *testing.T) {
+	b := createTestBackend(createLineServer(t, "", nil))
+	p := &TCPProber{Expect: "220", Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	err := p.Probe(context.Background(), b)
+
+	if err == nil {
+		t.Fatal("expected a silent server to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected probe to give up after its timeout, took %v", elapsed)
+	}
+}
+
+func TestTCPProber_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	b := createTestBackend(server.URL)
+	p := &TCPProber{
+		Send:    []byte("GET / HTTP/1.0\r\n\r\n"),
+		Expect:  "200 OK",
+		TLS:     &tls.Config{InsecureSkipVerify: true},
+		Timeout: time.Second,
+	}
+
+	if err := p.Probe(context.Background(), b); err != nil {
+		t.Errorf("expected probe over TLS to pass, got %v", err)
+	}
+
+	p.TLS = &tls.Config{}
+	if err := p.Probe(context.Background(), b); err == nil {
+		t.Error("expected untrusted certificate to fail verification")
+	}
+}
+
+func TestParseProber_TCPSendExpect(t *testing.T) {
+	p, err := ParseProber(map[string]string{
+		"type":            "tcp",
+		"send":            `PING\r\n`,
+		"expect":          "+PONG",
+		"tls":             "true",
+		"tls-server-name": "redis.internal",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcp, ok := p.(*TCPProber)
+	if !ok {
+		t.Fatalf("expected TCP prober, got %T", p)
+	}
+
+	if string(tcp.Send) != "PING\r\n" || tcp.Expect != "+PONG" {
+		t.Errorf("expected escapes to be interpreted, got send %q expect %q", tcp.Send, tcp.Expect)
+	}
+
+	if tcp.TLS == nil || tcp.TLS.ServerName != "redis.internal" {
+		t.Errorf("expected TLS config with server name, got %+v", tcp.TLS)
+	}
+}
+
+func TestParseProber_TCPInvalid(t *testing.T) {
+	testCases := []map[string]string{
+		{"send": `bad\q`},
+		{"expect-regex": "("},
+		{"tls": "maybe"},
+		{"tls-skip-verify": "true"},
+	}
+
+	for _, opts := range testCases {
+		if _, err := ParseProber(opts); err == nil {
+			t.Errorf("expected error for %v", opts)
+		}
+	}
+}
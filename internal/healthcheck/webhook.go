@@ -0,0 +1,137 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultWebhookRetries = 3
+	DefaultWebhookBackoff = time.Second
+	// webhookQueueSize is how many undelivered events are kept before new
+	// ones are dropped.
+	webhookQueueSize = 256
+)
+
+// Webhook POSTs every health event as JSON to a URL, retrying failed
+// deliveries with exponential backoff. Subscribe Notify to a Bus and start
+// Run.
+type Webhook struct {
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	queue   chan Event
+}
+
+type WebhookOption func(*Webhook)
+
+// WithRetries sets how many times a failed delivery is retried.
+func WithRetries(n int) WebhookOption {
+	return func(w *Webhook) {
+		if n >= 0 {
+			w.retries = n
+		}
+	}
+}
+
+// WithBackoff sets the wait before the first retry; it doubles after each.
+func WithBackoff(d time.Duration) WebhookOption {
+	return func(w *Webhook) {
+		if d > 0 {
+			w.backoff = d
+		}
+	}
+}
+
+func WithWebhookClient(c *http.Client) WebhookOption {
+	return func(w *Webhook) {
+		w.client = c
+	}
+}
+
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	w := &Webhook{
+		url:     url,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		retries: DefaultWebhookRetries,
+		backoff: DefaultWebhookBackoff,
+		queue:   make(chan Event, webhookQueueSize),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Notify queues e for delivery without blocking, dropping it if the queue is
+// full.
+func (w *Webhook) Notify(e Event) {
+	select {
+	case w.queue <- e:
+	default:
+		log.Printf("Webhook queue full, dropping event for %s\n", e.URL)
+	}
+}
+
+// Run delivers queued events in order until ctx is cancelled.
+func (w *Webhook) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-w.queue:
+			if err := w.deliver(ctx, e); err != nil {
+				log.Printf("Webhook delivery for %s failed: %s\n", e.URL, err)
+			}
+		}
+	}
+}
+
+func (w *Webhook) deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	backoff := w.backoff
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, body)
+		if err == nil || attempt >= w.retries {
+			return err
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
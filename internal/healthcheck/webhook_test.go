@@ -0,0 +1,140 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// createWebhookReceiver fails the first failures deliveries with 500 and
+// forwards every decoded payload it accepts to the returned channel.
+func createWebhookReceiver(t *testing.T, failures int32) (*httptest.Server, <-chan map[string]any, *atomic.Int32) {
+	received := make(chan map[string]any, 10)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- payload
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, received, &calls
+}
+
+func runWebhook(t *testing.T, w *Webhook) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go w.Run(ctx)
+}
+
+func TestWebhook_DeliversEvent(t *testing.T) {
+	server, received, _ := createWebhookReceiver(t, 0)
+	w := NewWebhook(server.URL)
+	runWebhook(t, w)
+
+	bus := NewBus()
+	bus.Subscribe(w.Notify)
+	bus.Publish(NewEvent(createTestBackend("http://10.0.0.1:8080"), false, "connection refused"))
+
+	select {
+	case payload := <-received:
+		if payload["backend"] != "http://10.0.0.1:8080" || payload["old_state"] != "up" ||
+			payload["new_state"] != "down" || payload["reason"] != "connection refused" {
+			t.Errorf("unexpected payload %v", payload)
+		}
+		if _, err := time.Parse(time.RFC3339, payload["timestamp"].(string)); err != nil {
+			t.Errorf("expected RFC 3339 timestamp, got %v", payload["timestamp"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the event to be delivered")
+	}
+}
+
+func TestWebhook_RetriesFailedDeliveries(t *testing.T) {
+	server, received, calls := createWebhookReceiver(t, 2)
+	w := NewWebhook(server.URL, WithRetries(3), WithBackoff(5*time.Millisecond))
+	runWebhook(t, w)
+
+	w.Notify(NewEvent(createTestBackend("http://10.0.0.1:8080"), true, "health check passed"))
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("expected the event to be delivered after retrying")
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls.Load())
+	}
+}
+
+func TestWebhook_GivesUpAfterRetries(t *testing.T) {
+	server, _, calls := createWebhookReceiver(t, 100)
+	w := NewWebhook(server.URL, WithRetries(2), WithBackoff(time.Millisecond))
+
+	err := w.deliver(context.Background(), NewEvent(createTestBackend("http://10.0.0.1:8080"), false, "test"))
+
+	if err == nil {
+		t.Error("expected delivery to fail")
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("expected 1 attempt plus 2 retries, got %d", calls.Load())
+	}
+}
+
+func TestWebhook_NotifyDoesNotBlock(t *testing.T) {
+	w := NewWebhook("http://127.0.0.1:1")
+	e := NewEvent(createTestBackend("http://10.0.0.1:8080"), false, "test")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < webhookQueueSize+10; i++ {
+			w.Notify(e)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Notify to drop events instead of blocking")
+	}
+}
+
+func TestWebhook_StopsOnCancel(t *testing.T) {
+	server, _, _ := createWebhookReceiver(t, 100)
+	w := NewWebhook(server.URL, WithBackoff(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	w.Notify(NewEvent(createTestBackend("http://10.0.0.1:8080"), false, "test"))
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return while waiting to retry")
+	}
+}
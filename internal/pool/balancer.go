@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// Balancer picks the backend that should serve the next request.
+type Balancer interface {
+	Next(backends []*backend.Backend, r *http.Request) *backend.Backend
+}
+
+// Updater is implemented by balancers that precompute state from the backend
+// set. The pool calls Update whenever membership or a backend's health changes.
+type Updater interface {
+	Update(backends []*backend.Backend)
+}
+
+// Options carries the settings strategies may need when they are built.
+type Options struct {
+	HashKey    KeyFunc
+	Replicas   int
+	LoadFactor float64
+	ClientIP   KeyFunc
+}
+
+type Factory func(opts Options) Balancer
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+func Register(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("pool: strategy registered twice: " + name)
+	}
+	registry[name] = f
+}
+
+func NewBalancer(name string, opts Options) (Balancer, error) {
+	registryMu.RLock()
+	f, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown balancing strategy %q", name)
+	}
+	return f(opts), nil
+}
+
+func Strategies() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
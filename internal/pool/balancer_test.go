@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewBalancer_RoundRobin(t *testing.T) {
+	b, err := NewBalancer(RoundRobinStrategy, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := b.(*RoundRobin); !ok {
+		t.Errorf("expected *RoundRobin, got %T", b)
+	}
+}
+
+func TestNewBalancer_Unknown(t *testing.T) {
+	if _, err := NewBalancer("does-not-exist", Options{}); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestNewBalancer_ReturnsFreshInstances(t *testing.T) {
+	b1, _ := NewBalancer(RoundRobinStrategy, Options{})
+	b2, _ := NewBalancer(RoundRobinStrategy, Options{})
+
+	if b1 == b2 {
+		t.Error("expected each call to return a new balancer")
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	names := Strategies()
+
+	if !slices.Contains(names, RoundRobinStrategy) {
+		t.Errorf("expected %q to be registered, got %v", RoundRobinStrategy, names)
+	}
+
+	if !slices.IsSorted(names) {
+		t.Errorf("expected strategies to be sorted, got %v", names)
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+
+	Register(RoundRobinStrategy, func(Options) Balancer { return NewRoundRobin() })
+}
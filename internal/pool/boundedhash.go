@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const (
+	BoundedHashStrategy = "bounded-hash"
+	DefaultLoadFactor   = 1.25
+)
+
+func init() {
+	Register(BoundedHashStrategy, func(opts Options) Balancer {
+		return NewBoundedHash(opts.HashKey, opts.Replicas, opts.LoadFactor)
+	})
+}
+
+// BoundedHash is consistent hashing with bounded loads: a backend may hold at
+// most LoadFactor times the average number of in-flight requests, and keys
+// whose backend is full spill clockwise to the next node with spare capacity.
+type BoundedHash struct {
+	*RingHash
+	factor float64
+}
+
+func NewBoundedHash(key KeyFunc, replicas int, factor float64) *BoundedHash {
+	if factor < 1 {
+		factor = DefaultLoadFactor
+	}
+	return &BoundedHash{
+		RingHash: NewRingHash(key, replicas),
+		factor:   factor,
+	}
+}
+
+func (h *BoundedHash) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	ring := h.ringFor(backends)
+	if len(ring) == 0 {
+		return nil
+	}
+
+	limit, ok := h.loadLimit(backends)
+	if !ok {
+		return nil
+	}
+
+	hash := hashKey(requestKey(h.key, r))
+	start := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= hash })
+
+	var fallback *backend.Backend
+	for i := 0; i < len(ring); i++ {
+		n := ring[(start+i)%len(ring)]
+		if !n.serving() {
+			continue
+		}
+		b := n.backend
+		if b.ActiveRequests() < limit {
+			return b
+		}
+		if fallback == nil {
+			fallback = b
+		}
+	}
+	// Loads moved while we walked; the key's own backend is the best bet.
+	return fallback
+}
+
+// loadLimit returns the per-backend in-flight cap, counting the request being
+// placed, and false when no backend is alive.
+func (h *BoundedHash) loadLimit(backends []*backend.Backend) (int64, bool) {
+	var total int64
+	alive := 0
+	for _, b := range backends {
+		if b.IsAvailable() {
+			total += b.ActiveRequests()
+			alive++
+		}
+	}
+	if alive == 0 {
+		return 0, false
+	}
+	return int64(math.Ceil(h.factor * float64(total+1) / float64(alive))), true
+}
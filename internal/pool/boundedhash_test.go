@@ -0,0 +1,138 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func TestBoundedHash_BehavesLikeRingWhenUnloaded(t *testing.T) {
+	ring := NewRingHash(nil, 0)
+	bounded := NewBoundedHash(nil, 0, 1.25)
+	backends := createHashBackends(4)
+
+	expected := mapKeys(ring, backends, 500)
+	got := mapKeys(bounded, backends, 500)
+
+	for key, b := range expected {
+		if got[key] != b {
+			t.Fatalf("expected key %s to map like the plain ring", key)
+		}
+	}
+}
+
+func TestBoundedHash_SpillsWhenOverCap(t *testing.T) {
+	h := NewBoundedHash(nil, 0, 1.25)
+	backends := createHashBackends(4)
+	req := requestForPath("/hot-key")
+
+	owner := h.Next(backends, req)
+	for i := 0; i < 10; i++ {
+		owner.IncActive()
+	}
+
+	// total 10 (+1) over 4 backends, cap = ceil(1.25 * 11 / 4) = 4
+	peer := h.Next(backends, req)
+	if peer == owner {
+		t.Fatal("expected hot key to spill to another backend")
+	}
+	if peer == nil {
+		t.Fatal("expected a spill target")
+	}
+
+	for i := 0; i < 10; i++ {
+		owner.DecActive()
+	}
+
+	if h.Next(backends, req) != owner {
+		t.Error("expected hot key to return to its owner once load drops")
+	}
+}
+
+func TestBoundedHash_NeverExceedsCap(t *testing.T) {
+	h := NewBoundedHash(nil, 0, 1.25)
+	backends := createHashBackends(4)
+	req := requestForPath("/same-key-for-everyone")
+
+	for i := 0; i < 40; i++ {
+		h.Next(backends, req).IncActive()
+	}
+
+	// 40 requests over 4 backends: no backend may hold more than ceil(1.25 * 40 / 4)
+	for i, b := range backends {
+		if b.ActiveRequests() > 13 {
+			t.Errorf("backend %d holds %d requests, above the load cap", i, b.ActiveRequests())
+		}
+	}
+}
+
+func TestBoundedHash_SkipsDeadBackends(t *testing.T) {
+	h := NewBoundedHash(nil, 0, 1.25)
+	backends := createHashBackends(3)
+	req := requestForPath("/key")
+
+	owner := h.Next(backends, req)
+	owner.SetAlive(false)
+
+	if peer := h.Next(backends, req); peer == owner || peer == nil {
+		t.Error("expected a different alive backend")
+	}
+}
+
+func TestBoundedHash_AllDead(t *testing.T) {
+	h := NewBoundedHash(nil, 0, 1.25)
+	backends := []*backend.Backend{createTestBackend("http://localhost:8080", false)}
+
+	if peer := h.Next(backends, requestForPath("/")); peer != nil {
+		t.Error("expected nil when all backends are dead")
+	}
+}
+
+func TestBoundedHash_DefaultFactor(t *testing.T) {
+	h := NewBoundedHash(nil, 0, 0)
+
+	if h.factor != DefaultLoadFactor {
+		t.Errorf("expected default factor %v, got %v", DefaultLoadFactor, h.factor)
+	}
+}
+
+func TestBoundedHash_Concurrent(t *testing.T) {
+	h := NewBoundedHash(nil, 0, 1.25)
+	backends := createHashBackends(3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			peer := h.Next(backends, requestForPath("/hot"))
+			if peer == nil {
+				t.Error("expected to get a peer")
+				return
+			}
+			peer.IncActive()
+			peer.DecActive()
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestBoundedHash_SlowStart(t *testing.T) {
+	backends := createHashBackends(2)
+	recovering := backends[1]
+	recovering.SlowStart = time.Hour
+	recovering.SetAlive(false)
+	recovering.SetAlive(true)
+
+	counts := make(map[*backend.Backend]int)
+	for _, b := range mapKeys(NewBoundedHash(nil, 0, 0), backends, 2000) {
+		counts[b]++
+	}
+
+	if counts[recovering] == 0 || counts[recovering] > 400 {
+		t.Errorf("expected recovering backend to get a small share of keys, got %d of 2000", counts[recovering])
+	}
+}
@@ -0,0 +1,46 @@
+package pool
+
+import (
+	"log"
+	"net/url"
+	"time"
+)
+
+const drainPollInterval = 100 * time.Millisecond
+
+// Drain stops routing new requests to the backend and removes it from the
+// pool once its in-flight requests finish or timeout elapses, whichever
+// comes first. The returned channel is closed after removal.
+func (s *ServerPool) Drain(backendUrl *url.URL, timeout time.Duration) (<-chan struct{}, bool) {
+	b := s.GetBackend(backendUrl)
+	if b == nil {
+		return nil, false
+	}
+
+	b.SetDraining(true)
+	log.Printf("%s [draining] %d in-flight\n", b.URL, b.ActiveRequests())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		tick := time.NewTicker(drainPollInterval)
+		defer tick.Stop()
+
+		for b.ActiveRequests() > 0 {
+			select {
+			case <-deadline.C:
+				log.Printf("%s drain timed out with %d in-flight\n", b.URL, b.ActiveRequests())
+				s.RemoveBackend(b.URL)
+				return
+			case <-tick.C:
+			}
+		}
+
+		s.RemoveBackend(b.URL)
+		log.Printf("%s [drained]\n", b.URL)
+	}()
+	return done, true
+}
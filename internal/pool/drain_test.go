@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDrain_StopsNewRequests(t *testing.T) {
+	p := New()
+	draining := createTestBackend("http://localhost:8080", true)
+	other := createTestBackend("http://localhost:8081", true)
+	p.AddBackend(draining)
+	p.AddBackend(other)
+	draining.IncActive()
+
+	done, ok := p.Drain(draining.URL, time.Second)
+	if !ok {
+		t.Fatal("expected backend to be found")
+	}
+
+	for i := 0; i < 4; i++ {
+		if p.GetNextPeer() != other {
+			t.Fatal("expected draining backend not to receive new requests")
+		}
+	}
+
+	if p.GetBackend(draining.URL) == nil {
+		t.Error("expected draining backend to stay in the pool while requests are in flight")
+	}
+
+	draining.DecActive()
+	<-done
+
+	if p.GetBackend(draining.URL) != nil {
+		t.Error("expected drained backend to be removed")
+	}
+}
+
+func TestDrain_RemovesIdleBackendImmediately(t *testing.T) {
+	p := New()
+	b := createTestBackend("http://localhost:8080", true)
+	p.AddBackend(b)
+
+	done, _ := p.Drain(b.URL, time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected idle backend to be drained right away")
+	}
+
+	if len(p.GetBackends()) != 0 {
+		t.Error("expected backend to be removed")
+	}
+}
+
+func TestDrain_TimesOut(t *testing.T) {
+	p := New()
+	b := createTestBackend("http://localhost:8080", true)
+	p.AddBackend(b)
+	b.IncActive()
+
+	start := time.Now()
+	done, _ := p.Drain(b.URL, 50*time.Millisecond)
+	<-done
+
+	if time.Since(start) > time.Second {
+		t.Error("expected drain to give up after the timeout")
+	}
+
+	if p.GetBackend(b.URL) != nil {
+		t.Error("expected backend to be removed after the timeout")
+	}
+
+	if b.ActiveRequests() != 1 {
+		t.Error("expected in-flight request to be left alone")
+	}
+}
+
+func TestDrain_UnknownBackend(t *testing.T) {
+	p := New()
+	u, _ := url.Parse("http://localhost:9999")
+
+	if _, ok := p.Drain(u, time.Second); ok {
+		t.Error("expected unknown backend to report false")
+	}
+}
+
+func TestDrain_MaglevExcludesDrainingBackend(t *testing.T) {
+	m := NewMaglev(nil, 0)
+	p := New(WithBalancer(m))
+	backends := createHashBackends(3)
+	for _, b := range backends {
+		p.AddBackend(b)
+	}
+	backends[1].IncActive()
+
+	p.Drain(backends[1].URL, time.Minute)
+
+	if len(m.table.Load().alive) != 2 {
+		t.Errorf("expected table to be rebuilt without the draining backend, got %d entries", len(m.table.Load().alive))
+	}
+}
@@ -0,0 +1,53 @@
+package pool
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/eltoncampos/load-balancer/internal/clientip"
+)
+
+// KeyFunc extracts the value hash-based strategies route on.
+type KeyFunc func(r *http.Request) string
+
+// ParseHashKey builds a KeyFunc from a spec: "path", "ip", "header:<name>"
+// or "query:<name>". The "ip" key uses clientIP when given, or the address of
+// the connecting peer otherwise.
+func ParseHashKey(spec string, clientIP KeyFunc) (KeyFunc, error) {
+	kind, name, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "path":
+		return func(r *http.Request) string {
+			return r.URL.Path
+		}, nil
+	case "ip":
+		if clientIP == nil {
+			clientIP = clientip.RemoteIP
+		}
+		return clientIP, nil
+	case "header":
+		if name == "" {
+			return nil, fmt.Errorf("hash key %q: missing header name", spec)
+		}
+		name = http.CanonicalHeaderKey(name)
+		return func(r *http.Request) string {
+			return r.Header.Get(name)
+		}, nil
+	case "query":
+		if name == "" {
+			return nil, fmt.Errorf("hash key %q: missing query parameter name", spec)
+		}
+		return func(r *http.Request) string {
+			return r.URL.Query().Get(name)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown hash key %q", spec)
+}
+
+func requestKey(key KeyFunc, r *http.Request) string {
+	if key == nil || r == nil {
+		return ""
+	}
+	return key(r)
+}
@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseHashKey(t *testing.T) {
+	req := httptest.NewRequest("GET", "/users/42?tenant=acme", nil)
+	req.Header.Set("X-User-Id", "u-7")
+	req.RemoteAddr = "10.0.0.9:51234"
+
+	testCases := []struct {
+		spec     string
+		expected string
+	}{
+		{spec: "path", expected: "/users/42"},
+		{spec: "ip", expected: "10.0.0.9"},
+		{spec: "header:X-User-Id", expected: "u-7"},
+		{spec: "header:x-user-id", expected: "u-7"},
+		{spec: "query:tenant", expected: "acme"},
+		{spec: "query:missing", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			key, err := ParseHashKey(tc.spec, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := key(req); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseHashKey_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "cookie", "header", "header:", "query:"} {
+		if _, err := ParseHashKey(spec, nil); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestParseHashKey_IPUsesClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.9:51234"
+
+	key, err := ParseHashKey("ip", func(*http.Request) string { return "198.51.100.1" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := key(req); got != "198.51.100.1" {
+		t.Errorf("expected resolved client IP, got %q", got)
+	}
+}
+
+func TestRequestKey_NilRequest(t *testing.T) {
+	key, _ := ParseHashKey("path", nil)
+
+	if got := requestKey(key, nil); got != "" {
+		t.Errorf("expected empty key for nil request, got %q", got)
+	}
+}
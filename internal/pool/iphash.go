@@ -0,0 +1,23 @@
+package pool
+
+import (
+	"github.com/eltoncampos/load-balancer/internal/clientip"
+)
+
+const IPHashStrategy = "ip-hash"
+
+func init() {
+	Register(IPHashStrategy, func(opts Options) Balancer {
+		return NewIPHash(opts.ClientIP, opts.Replicas)
+	})
+}
+
+// NewIPHash routes each client IP to a consistent alive backend. clientIP
+// should resolve the real client behind trusted proxies; without it the
+// connecting peer's address is used.
+func NewIPHash(clientIP KeyFunc, replicas int) *RingHash {
+	if clientIP == nil {
+		clientIP = clientip.RemoteIP
+	}
+	return NewRingHash(clientIP, replicas)
+}
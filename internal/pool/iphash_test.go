@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/clientip"
+)
+
+func requestFrom(remote, xff string) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remote
+	if xff != "" {
+		req.Header.Set("X-Forwarded-For", xff)
+	}
+	return req
+}
+
+func TestIPHash_SameClientSameBackend(t *testing.T) {
+	resolver, _ := clientip.NewResolver(clientip.HeaderXForwardedFor, []string{"10.0.0.0/8"})
+	h := NewIPHash(resolver.ClientIP, 0)
+	backends := createHashBackends(5)
+
+	first := h.Next(backends, requestFrom("10.0.0.1:1000", "198.51.100.7"))
+	for _, remote := range []string{"10.0.0.2:2000", "10.0.0.3:3000"} {
+		if h.Next(backends, requestFrom(remote, "198.51.100.7")) != first {
+			t.Fatal("expected the same client behind different proxies to keep its backend")
+		}
+	}
+}
+
+func TestIPHash_SpreadsClients(t *testing.T) {
+	resolver, _ := clientip.NewResolver(clientip.HeaderXForwardedFor, []string{"10.0.0.0/8"})
+	h := NewIPHash(resolver.ClientIP, 0)
+	backends := createHashBackends(3)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		req := requestFrom("10.0.0.1:1000", fmt.Sprintf("198.51.100.%d", i))
+		seen[h.Next(backends, req).URL.Host] = true
+	}
+
+	if len(seen) != len(backends) {
+		t.Errorf("expected clients to spread over all backends, got %d", len(seen))
+	}
+}
+
+func TestIPHash_IgnoresHeadersFromUntrustedPeers(t *testing.T) {
+	resolver, _ := clientip.NewResolver(clientip.HeaderXForwardedFor, []string{"10.0.0.0/8"})
+	h := NewIPHash(resolver.ClientIP, 0)
+	backends := createHashBackends(5)
+
+	direct := h.Next(backends, requestFrom("203.0.113.5:1000", ""))
+	for i := 0; i < 10; i++ {
+		spoofed := requestFrom("203.0.113.5:1000", fmt.Sprintf("198.51.100.%d", i))
+		if h.Next(backends, spoofed) != direct {
+			t.Fatal("expected spoofed X-Forwarded-For to be ignored")
+		}
+	}
+}
+
+func TestIPHash_SkipsDeadBackends(t *testing.T) {
+	h := NewIPHash(nil, 0)
+	backends := createHashBackends(3)
+	req := requestFrom("203.0.113.5:1000", "")
+
+	owner := h.Next(backends, req)
+	owner.SetAlive(false)
+
+	if peer := h.Next(backends, req); peer == owner || peer == nil {
+		t.Error("expected client to move to another alive backend")
+	}
+}
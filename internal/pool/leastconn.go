@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const LeastConnectionsStrategy = "least-connections"
+
+func init() {
+	Register(LeastConnectionsStrategy, func(Options) Balancer { return NewLeastConnections() })
+}
+
+// LeastConnections picks the alive backend with the fewest in-flight
+// requests. The scan starts at a rotating offset so ties are spread round
+// robin instead of always favouring the first backend.
+type LeastConnections struct {
+	current uint64
+}
+
+func NewLeastConnections() *LeastConnections {
+	return &LeastConnections{}
+}
+
+func (lc *LeastConnections) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+
+	start := int(atomic.AddUint64(&lc.current, uint64(1)) % uint64(len(backends)))
+
+	var best *backend.Backend
+	var bestActive int64
+
+	for i := 0; i < len(backends); i++ {
+		b := backends[(start+i)%len(backends)]
+		if !b.IsAvailable() {
+			continue
+		}
+		active := b.ActiveRequests()
+		if best == nil || active < bestActive {
+			best = b
+			bestActive = active
+		}
+	}
+	return best
+}
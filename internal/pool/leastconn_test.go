@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func TestLeastConnections_PicksFewestActive(t *testing.T) {
+	lc := NewLeastConnections()
+	busy := createTestBackend("http://localhost:8080", true)
+	idle := createTestBackend("http://localhost:8081", true)
+	busy.IncActive()
+	busy.IncActive()
+	idle.IncActive()
+
+	for i := 0; i < 4; i++ {
+		if peer := lc.Next([]*backend.Backend{busy, idle}, nil); peer != idle {
+			t.Fatal("expected backend with fewest active requests")
+		}
+	}
+}
+
+func TestLeastConnections_TiesBrokenRoundRobin(t *testing.T) {
+	lc := NewLeastConnections()
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", true),
+		createTestBackend("http://localhost:8081", true),
+		createTestBackend("http://localhost:8082", true),
+	}
+
+	seen := make(map[*backend.Backend]int)
+	for i := 0; i < 6; i++ {
+		seen[lc.Next(backends, nil)]++
+	}
+
+	for i, b := range backends {
+		if seen[b] != 2 {
+			t.Errorf("expected backend %d to be picked twice, got %d", i, seen[b])
+		}
+	}
+}
+
+func TestLeastConnections_SkipsDeadBackends(t *testing.T) {
+	lc := NewLeastConnections()
+	dead := createTestBackend("http://localhost:8080", false)
+	alive := createTestBackend("http://localhost:8081", true)
+	alive.IncActive()
+
+	if peer := lc.Next([]*backend.Backend{dead, alive}, nil); peer != alive {
+		t.Error("expected dead backend to be skipped even when idle")
+	}
+}
+
+func TestLeastConnections_AllDead(t *testing.T) {
+	lc := NewLeastConnections()
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", false),
+	}
+
+	if peer := lc.Next(backends, nil); peer != nil {
+		t.Error("expected nil when all backends are dead")
+	}
+}
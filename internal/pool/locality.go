@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// DefaultSpilloverThreshold is the inverse of Envoy's default overprovisioning
+// factor of 1.4: a zone keeps all of its local traffic while at least ~71% of
+// it is healthy.
+const DefaultSpilloverThreshold = 0.7
+
+type locality struct {
+	zone      string
+	threshold float64
+	random    func() float64
+}
+
+// WithZone makes the pool prefer backends in zone. Below threshold, the share
+// of traffic kept local shrinks with the healthy fraction of local backends,
+// min(1, healthy/threshold), and the rest spills to the other zones of the
+// active tier.
+func WithZone(zone string, threshold float64) Option {
+	return func(s *ServerPool) {
+		s.locality = locality{zone: zone, threshold: threshold, random: rand.Float64}
+	}
+}
+
+// localShare is the fraction of requests the local backends should receive.
+func (l locality) localShare(local []*backend.Backend) float64 {
+	healthy := 0
+	for _, b := range local {
+		if b.IsAvailable() {
+			healthy++
+		}
+	}
+	if healthy == 0 {
+		return 0
+	}
+	if l.threshold <= 0 {
+		return 1
+	}
+	return min(1, float64(healthy)/float64(len(local))/l.threshold)
+}
+
+// pick returns the backends of a tier that serve the next request: the local
+// ones for their share of requests, otherwise the remote ones, or the whole
+// tier when no remote backend is available.
+func (l locality) pick(local, remote, tier []*backend.Backend) []*backend.Backend {
+	share := l.localShare(local)
+	if share >= 1 || (share > 0 && l.random() < share) {
+		return local
+	}
+	if slices.ContainsFunc(remote, (*backend.Backend).IsAvailable) {
+		return remote
+	}
+	return tier
+}
+
+// splitZones records, for every tier, the backends in the pool's own zone and
+// those in the others.
+func (s *snapshot) splitZones() {
+	if s.locality.zone == "" {
+		return
+	}
+	s.local = make([][]*backend.Backend, len(s.tiers))
+	s.remote = make([][]*backend.Backend, len(s.tiers))
+	for i, tier := range s.tiers {
+		for _, b := range tier {
+			if b.Zone == s.locality.zone {
+				s.local[i] = append(s.local[i], b)
+			} else {
+				s.remote[i] = append(s.remote[i], b)
+			}
+		}
+	}
+}
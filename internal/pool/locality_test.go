@@ -0,0 +1,209 @@
+package pool
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func createZonedBackend(urlStr, zone string, priority int) *backend.Backend {
+	b := createTestBackend(urlStr, true)
+	b.Zone = zone
+	b.Priority = priority
+	return b
+}
+
+func createZonedPool(threshold float64, backends ...*backend.Backend) *ServerPool {
+	p := New(WithZone("a", threshold))
+	for _, b := range backends {
+		p.AddBackend(b)
+	}
+	return p
+}
+
+func TestLocality_PrefersLocalZone(t *testing.T) {
+	local1 := createZonedBackend("http://10.0.0.1:8080", "a", 0)
+	local2 := createZonedBackend("http://10.0.0.2:8080", "a", 0)
+	remote := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	p := createZonedPool(0.5, local1, remote, local2)
+
+	for i := 0; i < 10; i++ {
+		if p.GetNextPeer() == remote {
+			t.Fatal("expected remote zone not to receive traffic while local zone is healthy")
+		}
+	}
+}
+
+func TestLocality_StaysLocalAboveThreshold(t *testing.T) {
+	local1 := createZonedBackend("http://10.0.0.1:8080", "a", 0)
+	local2 := createZonedBackend("http://10.0.0.2:8080", "a", 0)
+	remote := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	p := createZonedPool(0.5, local1, local2, remote)
+
+	local1.SetAlive(false)
+
+	for i := 0; i < 4; i++ {
+		if p.GetNextPeer() != local2 {
+			t.Fatal("expected traffic to stay in zone at exactly the threshold")
+		}
+	}
+}
+
+func TestLocality_SpillsInProportionBelowThreshold(t *testing.T) {
+	locals := make([]*backend.Backend, 4)
+	for i := range locals {
+		locals[i] = createZonedBackend(fmt.Sprintf("http://10.0.0.%d:8080", i+1), "a", 0)
+	}
+	remote := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	p := createZonedPool(1, append(locals, remote)...)
+
+	locals[0].SetAlive(false)
+	locals[1].SetAlive(false)
+
+	counts := make(map[*backend.Backend]int)
+	for i := 0; i < 2000; i++ {
+		counts[p.GetNextPeer()]++
+	}
+
+	if counts[remote] < 800 || counts[remote] > 1200 {
+		t.Errorf("expected half the traffic to spill with half the zone healthy, got %d of 2000", counts[remote])
+	}
+	if counts[locals[2]] == 0 || counts[locals[3]] == 0 {
+		t.Error("expected healthy local backends to keep receiving traffic while spilling")
+	}
+	if counts[locals[0]] > 0 || counts[locals[1]] > 0 {
+		t.Error("expected dead backends to be skipped")
+	}
+}
+
+func TestLocality_SpilloverKeepsWeightedRoundRobinEven(t *testing.T) {
+	local1 := createZonedBackend("http://10.0.0.1:8080", "a", 0)
+	local2 := createZonedBackend("http://10.0.0.2:8080", "a", 0)
+	remote1 := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	remote2 := createZonedBackend("http://10.1.0.2:8080", "b", 0)
+	p := New(WithZone("a", 0.9), WithBalancer(NewWeightedRoundRobin()))
+	for _, b := range []*backend.Backend{local1, local2, remote1, remote2} {
+		p.AddBackend(b)
+	}
+
+	local1.SetAlive(false)
+
+	counts := make(map[*backend.Backend]int)
+	for i := 0; i < 10000; i++ {
+		counts[p.GetNextPeer()]++
+	}
+
+	if diff := counts[remote1] - counts[remote2]; diff < -1 || diff > 1 {
+		t.Errorf("expected spilled traffic to alternate between remote backends, got %d/%d", counts[remote1], counts[remote2])
+	}
+	if counts[local2] < 5000 || counts[local2] > 6100 {
+		t.Errorf("expected the healthy local backend to keep about 5/9 of traffic, got %d of 10000", counts[local2])
+	}
+}
+
+func TestLocality_LocalShare(t *testing.T) {
+	testCases := []struct {
+		name      string
+		healthy   int
+		total     int
+		threshold float64
+		expected  float64
+	}{
+		{name: "fully healthy", healthy: 4, total: 4, threshold: 0.7, expected: 1},
+		{name: "at threshold", healthy: 7, total: 10, threshold: 0.7, expected: 1},
+		{name: "below threshold", healthy: 2, total: 4, threshold: 0.8, expected: 0.625},
+		{name: "zone down", healthy: 0, total: 4, threshold: 0.7, expected: 0},
+		{name: "no local backends", healthy: 0, total: 0, threshold: 0.7, expected: 0},
+		{name: "zero threshold", healthy: 1, total: 4, threshold: 0, expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			local := make([]*backend.Backend, tc.total)
+			for i := range local {
+				local[i] = createTestBackend(fmt.Sprintf("http://10.0.0.%d:8080", i+1), i < tc.healthy)
+			}
+
+			l := locality{zone: "a", threshold: tc.threshold}
+			if got := l.localShare(local); math.Abs(got-tc.expected) > 1e-9 {
+				t.Errorf("expected share %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLocality_PickFallsBackToTierWhenRemoteDown(t *testing.T) {
+	local := []*backend.Backend{
+		createTestBackend("http://10.0.0.1:8080", true),
+		createTestBackend("http://10.0.0.2:8080", false),
+	}
+	remote := []*backend.Backend{createTestBackend("http://10.1.0.1:8080", false)}
+	tier := append(slices.Clone(local), remote...)
+	l := locality{zone: "a", threshold: 1, random: func() float64 { return 0.9 }}
+
+	if got := l.pick(local, remote, tier); !sameMembers(got, tier) {
+		t.Error("expected spilled requests to use the whole tier when no remote backend is available")
+	}
+}
+
+func TestLocality_SpillsWhenLocalZoneDown(t *testing.T) {
+	local := createZonedBackend("http://10.0.0.1:8080", "a", 0)
+	remote := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	p := createZonedPool(0, local, remote)
+
+	local.SetAlive(false)
+
+	if p.GetNextPeer() != remote {
+		t.Error("expected remote zone to serve when no local backend is healthy")
+	}
+}
+
+func TestLocality_NoLocalBackends(t *testing.T) {
+	remote1 := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	remote2 := createZonedBackend("http://10.2.0.1:8080", "c", 0)
+	p := createZonedPool(0.7, remote1, remote2)
+
+	seen := make(map[*backend.Backend]bool)
+	for i := 0; i < 4; i++ {
+		seen[p.GetNextPeer()] = true
+	}
+
+	if !seen[remote1] || !seen[remote2] {
+		t.Error("expected all zones to be used when none is local")
+	}
+}
+
+func TestLocality_RespectsPriorityTiers(t *testing.T) {
+	remotePrimary := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	localBackup := createZonedBackend("http://10.0.0.1:8080", "a", 1)
+	p := createZonedPool(0.7, localBackup, remotePrimary)
+
+	if p.GetNextPeer() != remotePrimary {
+		t.Error("expected the primary tier to win over locality")
+	}
+
+	remotePrimary.SetAlive(false)
+	if p.GetNextPeer() != localBackup {
+		t.Error("expected the backup tier once primaries are down")
+	}
+}
+
+func TestLocality_DisabledWithoutZone(t *testing.T) {
+	p := New()
+	a := createZonedBackend("http://10.0.0.1:8080", "a", 0)
+	b := createZonedBackend("http://10.1.0.1:8080", "b", 0)
+	p.AddBackend(a)
+	p.AddBackend(b)
+
+	seen := make(map[*backend.Backend]bool)
+	for i := 0; i < 4; i++ {
+		seen[p.GetNextPeer()] = true
+	}
+
+	if !seen[a] || !seen[b] {
+		t.Error("expected zones to be ignored when the pool has no zone")
+	}
+}
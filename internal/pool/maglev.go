@@ -0,0 +1,169 @@
+package pool
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const (
+	MaglevStrategy = "maglev"
+	// DefaultMaglevTableSize is prime, as the permutation scheme requires,
+	// and large enough to keep imbalance under 1% for hundreds of backends.
+	DefaultMaglevTableSize = 65537
+)
+
+func init() {
+	Register(MaglevStrategy, func(opts Options) Balancer {
+		return NewMaglev(opts.HashKey, DefaultMaglevTableSize)
+	})
+}
+
+// Maglev implements Google's Maglev hashing: every alive backend fills slots
+// of a fixed-size lookup table following its own permutation, giving O(1)
+// lookups, near-even spread and little disruption when the set changes. The
+// table is rebuilt by Update whenever membership or health changes.
+type Maglev struct {
+	key  KeyFunc
+	size int
+
+	mux    sync.Mutex
+	table  atomic.Pointer[maglevTable]
+	tables map[memberKey]*maglevTable
+}
+
+type maglevTable struct {
+	members []*backend.Backend
+	alive   []*backend.Backend
+	entries []int32
+}
+
+// NewMaglev returns a Maglev balancer with a lookup table of size slots. A
+// size that is not prime falls back to DefaultMaglevTableSize.
+func NewMaglev(key KeyFunc, size int) *Maglev {
+	if key == nil {
+		key, _ = ParseHashKey("path", nil)
+	}
+	if !isPrime(size) {
+		size = DefaultMaglevTableSize
+	}
+	return &Maglev{
+		key:  key,
+		size: size,
+	}
+}
+
+// Update rebuilds the table for backends and drops the tables built for other
+// lists, whose health may have changed too.
+func (m *Maglev) Update(backends []*backend.Backend) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.tables = nil
+	m.store(buildMaglevTable(backends, m.size))
+}
+
+func (m *Maglev) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	t := m.tableFor(backends)
+	if len(t.alive) == 0 {
+		return nil
+	}
+
+	slot := int(hashKey(requestKey(m.key, r)) % uint64(len(t.entries)))
+	for i := 0; i < len(t.entries); i++ {
+		b := t.alive[t.entries[(slot+i)%len(t.entries)]]
+		if b.IsAvailable() {
+			return b
+		}
+	}
+	return nil
+}
+
+// tableFor returns the table for backends, building it if no table for that
+// list is cached.
+func (m *Maglev) tableFor(backends []*backend.Backend) *maglevTable {
+	if t := m.table.Load(); t != nil && sameMembers(t.members, backends) {
+		return t
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	t, ok := m.tables[membersKey(backends)]
+	if !ok {
+		t = buildMaglevTable(backends, m.size)
+	}
+	m.store(t)
+	return t
+}
+
+// store makes t the current table and caches it. Callers must hold m.mux.
+func (m *Maglev) store(t *maglevTable) {
+	if m.tables == nil || len(m.tables) >= maxCachedMembers {
+		m.tables = make(map[memberKey]*maglevTable)
+	}
+	m.tables[membersKey(t.members)] = t
+	m.table.Store(t)
+}
+
+func buildMaglevTable(backends []*backend.Backend, size int) *maglevTable {
+	t := &maglevTable{members: backends}
+	for _, b := range backends {
+		if b.IsAvailable() {
+			t.alive = append(t.alive, b)
+		}
+	}
+	if len(t.alive) == 0 {
+		return t
+	}
+
+	offsets := make([]uint64, len(t.alive))
+	skips := make([]uint64, len(t.alive))
+	for i, b := range t.alive {
+		name := b.URL.String()
+		offsets[i] = hashKey(name) % uint64(size)
+		skips[i] = maglevSkip(name)%uint64(size-1) + 1
+	}
+
+	t.entries = make([]int32, size)
+	for i := range t.entries {
+		t.entries[i] = -1
+	}
+
+	next := make([]uint64, len(t.alive))
+	filled := 0
+	for {
+		for i := range t.alive {
+			c := (offsets[i] + next[i]*skips[i]) % uint64(size)
+			for t.entries[c] >= 0 {
+				next[i]++
+				c = (offsets[i] + next[i]*skips[i]) % uint64(size)
+			}
+			t.entries[c] = int32(i)
+			next[i]++
+			filled++
+			if filled == size {
+				return t
+			}
+		}
+	}
+}
+
+func maglevSkip(name string) uint64 {
+	h := fnv.New64()
+	h.Write([]byte(name))
+	return mix64(h.Sum64())
+}
+
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for i := 2; i*i <= n; i++ {
+		if n%i == 0 {
+			return false
+		}
+	}
+	return true
+}
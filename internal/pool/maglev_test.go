@@ -0,0 +1,185 @@
+package pool
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func TestMaglev_SameKeySameBackend(t *testing.T) {
+	m := NewMaglev(nil, 0)
+	backends := createHashBackends(5)
+
+	first := m.Next(backends, requestForPath("/cache/item"))
+	for i := 0; i < 10; i++ {
+		if peer := m.Next(backends, requestForPath("/cache/item")); peer != first {
+			t.Fatal("expected the same key to always map to the same backend")
+		}
+	}
+}
+
+func TestMaglev_TableIsBalanced(t *testing.T) {
+	backends := createHashBackends(7)
+	table := buildMaglevTable(backends, DefaultMaglevTableSize)
+
+	counts := make([]int, len(table.alive))
+	for _, e := range table.entries {
+		counts[e]++
+	}
+
+	ideal := DefaultMaglevTableSize / len(backends)
+	for i, c := range counts {
+		if c < ideal-ideal/100 || c > ideal+ideal/100 {
+			t.Errorf("backend %d owns %d slots, expected within 1%% of %d", i, c, ideal)
+		}
+	}
+}
+
+func TestMaglev_MinimalDisruptionOnRemoval(t *testing.T) {
+	backends := createHashBackends(10)
+	before := buildMaglevTable(backends, DefaultMaglevTableSize)
+
+	removed := backends[4]
+	remaining := append(append([]*backend.Backend{}, backends[:4]...), backends[5:]...)
+	after := buildMaglevTable(remaining, DefaultMaglevTableSize)
+
+	moved := 0
+	for i := range before.entries {
+		prev := before.alive[before.entries[i]]
+		if prev != removed && after.alive[after.entries[i]] != prev {
+			moved++
+		}
+	}
+
+	// Maglev trades a little extra churn for balance; it should stay far
+	// below the ~90% a modulo hash would move here.
+	if moved > DefaultMaglevTableSize/10 {
+		t.Errorf("expected few slots of surviving backends to move, got %d", moved)
+	}
+}
+
+func TestMaglev_RebuildsOnSetAlive(t *testing.T) {
+	m := NewMaglev(nil, 0)
+	p := New(WithBalancer(m))
+	backends := createHashBackends(4)
+	for _, b := range backends {
+		p.AddBackend(b)
+	}
+
+	before := mapKeys(m, p.GetBackends(), 500)
+
+	dead := backends[2]
+	dead.SetAlive(false)
+
+	if len(m.table.Load().alive) != 3 {
+		t.Fatalf("expected table to be rebuilt with 3 alive backends, got %d", len(m.table.Load().alive))
+	}
+
+	for key := range before {
+		if p.GetPeer(requestForPath(key)) == dead {
+			t.Fatalf("key %s routed to dead backend", key)
+		}
+	}
+
+	dead.SetAlive(true)
+	for key, b := range before {
+		if p.GetPeer(requestForPath(key)) != b {
+			t.Fatalf("key %s did not return to its backend after recovery", key)
+		}
+	}
+}
+
+func TestMaglev_RebuildsOnAddBackend(t *testing.T) {
+	m := NewMaglev(nil, 0)
+	p := New(WithBalancer(m))
+	backends := createHashBackends(3)
+	p.AddBackend(backends[0])
+	p.AddBackend(backends[1])
+
+	p.AddBackend(backends[2])
+
+	seen := make(map[*backend.Backend]bool)
+	for key := range mapKeys(m, p.GetBackends(), 300) {
+		seen[p.GetPeer(requestForPath(key))] = true
+	}
+
+	if !seen[backends[2]] {
+		t.Error("expected new backend to receive keys")
+	}
+}
+
+func TestMaglev_AllDead(t *testing.T) {
+	m := NewMaglev(nil, 0)
+	backends := createHashBackends(2)
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+
+	if peer := m.Next(backends, requestForPath("/")); peer != nil {
+		t.Error("expected nil when all backends are dead")
+	}
+
+	if peer := m.Next(nil, requestForPath("/")); peer != nil {
+		t.Error("expected nil for empty backend list")
+	}
+}
+
+func TestMaglev_SkipsBackendThatDiedBeforeRebuild(t *testing.T) {
+	m := NewMaglev(nil, 13)
+	backends := createHashBackends(3)
+	m.Update(backends)
+
+	// Flip without going through a pool, so the table is stale.
+	backends[0].SetAlive(false)
+
+	for i := 0; i < 50; i++ {
+		if m.Next(backends, requestForPath(fmt.Sprintf("/%d", i))) == backends[0] {
+			t.Fatal("expected stale table entries for dead backends to be skipped")
+		}
+	}
+}
+
+func TestNewMaglev_RejectsInvalidTableSizes(t *testing.T) {
+	testCases := []struct {
+		size     int
+		expected int
+	}{
+		{size: 0, expected: DefaultMaglevTableSize},
+		{size: 1, expected: DefaultMaglevTableSize},
+		{size: 12, expected: DefaultMaglevTableSize},
+		{size: 2, expected: 2},
+		{size: 13, expected: 13},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.size), func(t *testing.T) {
+			m := NewMaglev(nil, tc.size)
+			if m.size != tc.expected {
+				t.Errorf("expected table size %d, got %d", tc.expected, m.size)
+			}
+
+			if peer := m.Next(createHashBackends(3), requestForPath("/item")); peer == nil {
+				t.Error("expected a backend to be picked")
+			}
+		})
+	}
+}
+
+func TestMaglev_CachesTablePerBackendList(t *testing.T) {
+	m := NewMaglev(nil, 13)
+	backends := createHashBackends(4)
+	local, remote := backends[:2:2], backends[2:]
+
+	first := m.tableFor(local)
+	m.tableFor(remote)
+
+	if m.tableFor(local) != first {
+		t.Error("expected alternating backend lists not to rebuild the table")
+	}
+
+	m.Update(local)
+	if m.tableFor(local) == first {
+		t.Error("expected Update to rebuild the table")
+	}
+}
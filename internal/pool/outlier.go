@@ -0,0 +1,195 @@
+package pool
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// OutlierConfig controls passive health checking: backends that fail real
+// traffic are ejected from rotation for a while. A zero threshold disables
+// that particular check.
+type OutlierConfig struct {
+	// Consecutive5xx ejects after this many 5xx responses or round trip
+	// errors in a row.
+	Consecutive5xx int
+	// ConsecutiveGatewayErrors ejects after this many 502, 503, 504 or round
+	// trip errors in a row.
+	ConsecutiveGatewayErrors int
+	// ErrorRate ejects once this fraction of requests in the current window
+	// failed, provided at least MinRequests were seen.
+	ErrorRate   float64
+	MinRequests int
+	Window      time.Duration
+	// BaseEjectionTime is doubled on every repeated ejection, up to
+	// MaxEjectionTime.
+	BaseEjectionTime time.Duration
+	MaxEjectionTime  time.Duration
+	// MaxEjectionPercent caps the share of the pool ejected at once. At least
+	// one backend may be ejected, but never the last one.
+	MaxEjectionPercent int
+}
+
+// DefaultOutlierConfig follows Envoy's defaults, with error-rate detection
+// left off.
+func DefaultOutlierConfig() OutlierConfig {
+	return OutlierConfig{
+		Consecutive5xx:     5,
+		MinRequests:        20,
+		Window:             10 * time.Second,
+		BaseEjectionTime:   30 * time.Second,
+		MaxEjectionTime:    300 * time.Second,
+		MaxEjectionPercent: 10,
+	}
+}
+
+// WithOutlierDetection ejects backends whose proxied responses trip any of
+// the thresholds in cfg.
+func WithOutlierDetection(cfg OutlierConfig) Option {
+	return func(s *ServerPool) {
+		s.outliers = &outlierDetector{
+			cfg:      cfg,
+			backends: s.GetBackends,
+			stats:    make(map[*backend.Backend]*outlierStats),
+		}
+	}
+}
+
+type outlierDetector struct {
+	cfg      OutlierConfig
+	backends func() []*backend.Backend
+
+	mux   sync.Mutex
+	stats map[*backend.Backend]*outlierStats
+}
+
+type outlierStats struct {
+	consecutive5xx     int
+	consecutiveGateway int
+	windowStart        time.Time
+	requests           int
+	failures           int
+	ejections          int
+	ejectedUntil       time.Time
+}
+
+// watch subscribes the detector to b's responses and returns the function
+// that undoes it.
+func (d *outlierDetector) watch(b *backend.Backend) func() {
+	cancel := b.OnResponse(d.observe)
+	return func() {
+		cancel()
+		d.mux.Lock()
+		delete(d.stats, b)
+		d.mux.Unlock()
+	}
+}
+
+func (d *outlierDetector) observe(b *backend.Backend, status int, err error) {
+	if b.IsEjected() {
+		// Late responses from requests sent before the ejection.
+		return
+	}
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	st, ok := d.stats[b]
+	if !ok {
+		st = &outlierStats{}
+		d.stats[b] = st
+	}
+
+	now := time.Now()
+	if now.Sub(st.windowStart) >= d.cfg.Window {
+		st.windowStart = now
+		st.requests, st.failures = 0, 0
+	}
+
+	failed := err != nil || status >= 500
+	gateway := err != nil || status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+
+	st.requests++
+	if failed {
+		st.failures++
+		st.consecutive5xx++
+	} else {
+		st.consecutive5xx = 0
+	}
+	if gateway {
+		st.consecutiveGateway++
+	} else {
+		st.consecutiveGateway = 0
+	}
+
+	reason := d.outlierReason(st)
+	if reason == "" {
+		return
+	}
+
+	if !d.canEject() {
+		log.Printf("%s is an outlier (%s) but the ejection cap is reached\n", b.URL, reason)
+		return
+	}
+
+	// A backend that behaved for a full MaxEjectionTime starts over.
+	if now.Sub(st.ejectedUntil) > d.cfg.MaxEjectionTime {
+		st.ejections = 0
+	}
+	duration := d.ejectionTime(st.ejections)
+	st.ejections++
+	st.ejectedUntil = now.Add(duration)
+	st.consecutive5xx, st.consecutiveGateway = 0, 0
+	st.windowStart = time.Time{}
+
+	log.Printf("%s [ejected] for %s: %s\n", b.URL, duration, reason)
+	b.SetEjected(true)
+	time.AfterFunc(duration, func() {
+		b.SetEjected(false)
+		log.Printf("%s [ejection ended]\n", b.URL)
+	})
+}
+
+func (d *outlierDetector) outlierReason(st *outlierStats) string {
+	switch {
+	case d.cfg.Consecutive5xx > 0 && st.consecutive5xx >= d.cfg.Consecutive5xx:
+		return fmt.Sprintf("%d consecutive 5xx", st.consecutive5xx)
+	case d.cfg.ConsecutiveGatewayErrors > 0 && st.consecutiveGateway >= d.cfg.ConsecutiveGatewayErrors:
+		return fmt.Sprintf("%d consecutive gateway errors", st.consecutiveGateway)
+	case d.cfg.ErrorRate > 0 && st.requests >= d.cfg.MinRequests &&
+		float64(st.failures)/float64(st.requests) >= d.cfg.ErrorRate:
+		return fmt.Sprintf("%d of %d requests failed", st.failures, st.requests)
+	}
+	return ""
+}
+
+// canEject reports whether one more backend may be ejected without breaking
+// MaxEjectionPercent. Callers must hold d.mux, which also serializes
+// ejections.
+func (d *outlierDetector) canEject() bool {
+	backends := d.backends()
+	ejected := 0
+	for _, b := range backends {
+		if b.IsEjected() {
+			ejected++
+		}
+	}
+	limit := min(max(len(backends)*d.cfg.MaxEjectionPercent/100, 1), len(backends)-1)
+	return ejected < limit
+}
+
+func (d *outlierDetector) ejectionTime(ejections int) time.Duration {
+	t := d.cfg.BaseEjectionTime
+	for range ejections {
+		if t >= d.cfg.MaxEjectionTime {
+			break
+		}
+		t *= 2
+	}
+	return min(t, d.cfg.MaxEjectionTime)
+}
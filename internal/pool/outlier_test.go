@@ -0,0 +1,251 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// stubTransport answers every round trip with status, or fails when status
+// is 0.
+type stubTransport struct {
+	status atomic.Int32
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := int(t.status.Load())
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{
+		StatusCode: status,
+		Body:       http.NoBody,
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func createStubBackend(urlStr string, status int) (*backend.Backend, *stubTransport) {
+	u, _ := url.Parse(urlStr)
+	t := &stubTransport{}
+	t.status.Store(int32(status))
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	proxy.Transport = t
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, _ error) {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return backend.New(u, proxy), t
+}
+
+func createOutlierPool(cfg OutlierConfig, n int) (*ServerPool, []*backend.Backend, []*stubTransport) {
+	p := New(WithOutlierDetection(cfg))
+	backends := make([]*backend.Backend, n)
+	transports := make([]*stubTransport, n)
+	for i := range backends {
+		backends[i], transports[i] = createStubBackend(fmt.Sprintf("http://10.0.0.%d:8080", i+1), http.StatusOK)
+		p.AddBackend(backends[i])
+	}
+	return p, backends, transports
+}
+
+func serve(b *backend.Backend, times int) {
+	for range times {
+		b.ReverseProxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+}
+
+func testOutlierConfig() OutlierConfig {
+	cfg := DefaultOutlierConfig()
+	cfg.MaxEjectionPercent = 50
+	return cfg
+}
+
+func TestOutlier_Consecutive5xx(t *testing.T) {
+	p, backends, transports := createOutlierPool(testOutlierConfig(), 4)
+	transports[0].status.Store(http.StatusInternalServerError)
+
+	serve(backends[0], 4)
+	if backends[0].IsEjected() {
+		t.Fatal("expected no ejection below the threshold")
+	}
+
+	serve(backends[0], 1)
+	if !backends[0].IsEjected() {
+		t.Fatal("expected ejection after 5 consecutive 5xx")
+	}
+
+	for i := 0; i < 10; i++ {
+		if p.GetNextPeer() == backends[0] {
+			t.Fatal("expected ejected backend not to be selected")
+		}
+	}
+}
+
+func TestOutlier_SuccessResetsConsecutiveCount(t *testing.T) {
+	_, backends, transports := createOutlierPool(testOutlierConfig(), 4)
+
+	for i := 0; i < 3; i++ {
+		transports[0].status.Store(http.StatusInternalServerError)
+		serve(backends[0], 4)
+		transports[0].status.Store(http.StatusOK)
+		serve(backends[0], 1)
+	}
+
+	if backends[0].IsEjected() {
+		t.Error("expected interleaved successes to prevent ejection")
+	}
+}
+
+func TestOutlier_ConsecutiveGatewayErrors(t *testing.T) {
+	cfg := testOutlierConfig()
+	cfg.Consecutive5xx = 0
+	cfg.ConsecutiveGatewayErrors = 3
+	_, backends, transports := createOutlierPool(cfg, 4)
+
+	transports[0].status.Store(http.StatusInternalServerError)
+	serve(backends[0], 10)
+	if backends[0].IsEjected() {
+		t.Fatal("expected plain 500s not to count as gateway errors")
+	}
+
+	transports[0].status.Store(0)
+	serve(backends[0], 3)
+	if !backends[0].IsEjected() {
+		t.Error("expected ejection after 3 round trip errors")
+	}
+}
+
+func TestOutlier_ErrorRate(t *testing.T) {
+	cfg := testOutlierConfig()
+	cfg.Consecutive5xx = 0
+	cfg.ErrorRate = 0.5
+	cfg.MinRequests = 10
+	_, backends, transports := createOutlierPool(cfg, 4)
+
+	for i := 0; i < 4; i++ {
+		transports[0].status.Store(http.StatusServiceUnavailable)
+		serve(backends[0], 1)
+		transports[0].status.Store(http.StatusOK)
+		serve(backends[0], 1)
+	}
+	if backends[0].IsEjected() {
+		t.Fatal("expected no ejection before MinRequests")
+	}
+
+	transports[0].status.Store(http.StatusServiceUnavailable)
+	serve(backends[0], 2)
+	if !backends[0].IsEjected() {
+		t.Error("expected ejection once half of the window failed")
+	}
+}
+
+func TestOutlier_MaxEjectionPercent(t *testing.T) {
+	_, backends, transports := createOutlierPool(testOutlierConfig(), 4)
+
+	for i, b := range backends {
+		transports[i].status.Store(http.StatusInternalServerError)
+		serve(b, 5)
+	}
+
+	ejected := 0
+	for _, b := range backends {
+		if b.IsEjected() {
+			ejected++
+		}
+	}
+	if ejected != 2 {
+		t.Errorf("expected 50%% of 4 backends to be ejected, got %d", ejected)
+	}
+}
+
+func TestOutlier_NeverEjectsWholePool(t *testing.T) {
+	cfg := testOutlierConfig()
+	cfg.MaxEjectionPercent = 100
+	_, backends, transports := createOutlierPool(cfg, 2)
+
+	for i, b := range backends {
+		transports[i].status.Store(http.StatusInternalServerError)
+		serve(b, 5)
+	}
+
+	if backends[0].IsEjected() == backends[1].IsEjected() {
+		t.Error("expected exactly one of two backends to be ejected")
+	}
+}
+
+func TestOutlier_EjectionExpires(t *testing.T) {
+	cfg := testOutlierConfig()
+	cfg.BaseEjectionTime = 20 * time.Millisecond
+	p, backends, transports := createOutlierPool(cfg, 2)
+
+	transports[0].status.Store(http.StatusInternalServerError)
+	serve(backends[0], 5)
+	if !backends[0].IsEjected() {
+		t.Fatal("expected backend to be ejected")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if backends[0].IsEjected() {
+		t.Fatal("expected ejection to end after the base ejection time")
+	}
+
+	seen := false
+	for i := 0; i < 4; i++ {
+		seen = seen || p.GetNextPeer() == backends[0]
+	}
+	if !seen {
+		t.Error("expected backend to receive traffic again")
+	}
+}
+
+func TestOutlier_EjectionTimeBackoff(t *testing.T) {
+	d := &outlierDetector{cfg: OutlierConfig{
+		BaseEjectionTime: 30 * time.Second,
+		MaxEjectionTime:  100 * time.Second,
+	}}
+
+	expected := []time.Duration{30 * time.Second, 60 * time.Second, 100 * time.Second, 100 * time.Second}
+	for n, want := range expected {
+		if got := d.ejectionTime(n); got != want {
+			t.Errorf("ejection %d: expected %s, got %s", n, want, got)
+		}
+	}
+}
+
+func TestOutlier_RemovedBackendIsForgotten(t *testing.T) {
+	p, backends, transports := createOutlierPool(testOutlierConfig(), 4)
+	transports[0].status.Store(http.StatusInternalServerError)
+	serve(backends[0], 2)
+
+	p.RemoveBackend(backends[0].URL)
+	serve(backends[0], 5)
+
+	if backends[0].IsEjected() {
+		t.Error("expected a removed backend not to be watched")
+	}
+
+	if len(p.outliers.stats) != 0 {
+		t.Errorf("expected no stats for removed backend, got %d", len(p.outliers.stats))
+	}
+}
+
+func TestOutlier_DisabledByDefault(t *testing.T) {
+	p := New()
+	b, _ := createStubBackend("http://10.0.0.1:8080", http.StatusInternalServerError)
+	p.AddBackend(b)
+
+	serve(b, 20)
+
+	if b.IsEjected() {
+		t.Error("expected no outlier detection unless enabled")
+	}
+}
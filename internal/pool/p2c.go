@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const PowerOfTwoChoicesStrategy = "p2c"
+
+func init() {
+	Register(PowerOfTwoChoicesStrategy, func(Options) Balancer { return NewPowerOfTwoChoices() })
+}
+
+// PowerOfTwoChoices samples two alive backends at random and keeps the one
+// with the lower load score, avoiding a full scan of large pools.
+type PowerOfTwoChoices struct {
+	intN func(n int) int
+}
+
+func NewPowerOfTwoChoices() *PowerOfTwoChoices {
+	return &PowerOfTwoChoices{intN: rand.IntN}
+}
+
+func (p *PowerOfTwoChoices) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	switch len(backends) {
+	case 0:
+		return nil
+	case 1:
+		if backends[0].IsAvailable() {
+			return backends[0]
+		}
+		return nil
+	}
+
+	i := p.intN(len(backends))
+	j := p.intN(len(backends) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := backends[i], backends[j]
+	if a.IsAvailable() && b.IsAvailable() {
+		return pickLowerScore(a, b)
+	}
+
+	alive := make([]*backend.Backend, 0, len(backends))
+	for _, b := range backends {
+		if b.IsAvailable() {
+			alive = append(alive, b)
+		}
+	}
+	switch len(alive) {
+	case 0:
+		return nil
+	case 1:
+		return alive[0]
+	}
+
+	i = p.intN(len(alive))
+	j = p.intN(len(alive) - 1)
+	if j >= i {
+		j++
+	}
+	return pickLowerScore(alive[i], alive[j])
+}
+
+func pickLowerScore(a, b *backend.Backend) *backend.Backend {
+	if score(b) < score(a) {
+		return b
+	}
+	return a
+}
+
+// score estimates the time a new request would wait on b: its smoothed
+// latency multiplied by the number of requests it would be sharing with.
+// Backends without samples yet score near zero so they get probed quickly.
+func score(b *backend.Backend) float64 {
+	return float64(b.ActiveRequests()+1) * float64(b.LatencyEWMA()+1)
+}
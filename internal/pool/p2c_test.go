@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func TestPowerOfTwoChoices_PicksLowerLatency(t *testing.T) {
+	p := NewPowerOfTwoChoices()
+	slow := createTestBackend("http://localhost:8080", true)
+	fast := createTestBackend("http://localhost:8081", true)
+	slow.ObserveLatency(100 * time.Millisecond)
+	fast.ObserveLatency(10 * time.Millisecond)
+
+	for i := 0; i < 20; i++ {
+		if peer := p.Next([]*backend.Backend{slow, fast}, nil); peer != fast {
+			t.Fatal("expected backend with lower latency to be picked")
+		}
+	}
+}
+
+func TestPowerOfTwoChoices_AccountsForInFlight(t *testing.T) {
+	p := NewPowerOfTwoChoices()
+	busy := createTestBackend("http://localhost:8080", true)
+	idle := createTestBackend("http://localhost:8081", true)
+	busy.ObserveLatency(10 * time.Millisecond)
+	idle.ObserveLatency(20 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		busy.IncActive()
+	}
+
+	if peer := p.Next([]*backend.Backend{busy, idle}, nil); peer != idle {
+		t.Error("expected idle backend to win over a faster but loaded one")
+	}
+}
+
+func TestPowerOfTwoChoices_SkipsDeadBackends(t *testing.T) {
+	p := NewPowerOfTwoChoices()
+	alive := createTestBackend("http://localhost:8082", true)
+	alive.ObserveLatency(time.Second)
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", false),
+		createTestBackend("http://localhost:8081", false),
+		alive,
+	}
+
+	for i := 0; i < 20; i++ {
+		if peer := p.Next(backends, nil); peer != alive {
+			t.Fatal("expected only the alive backend to be picked")
+		}
+	}
+}
+
+func TestPowerOfTwoChoices_AllDead(t *testing.T) {
+	p := NewPowerOfTwoChoices()
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", false),
+		createTestBackend("http://localhost:8081", false),
+	}
+
+	if peer := p.Next(backends, nil); peer != nil {
+		t.Error("expected nil when all backends are dead")
+	}
+
+	if peer := p.Next(backends[:1], nil); peer != nil {
+		t.Error("expected nil for a single dead backend")
+	}
+}
+
+func TestPowerOfTwoChoices_SamplesDistinctBackends(t *testing.T) {
+	p := NewPowerOfTwoChoices()
+	p.intN = func(n int) int { return 0 }
+	a := createTestBackend("http://localhost:8080", true)
+	b := createTestBackend("http://localhost:8081", true)
+	a.ObserveLatency(100 * time.Millisecond)
+	b.ObserveLatency(10 * time.Millisecond)
+
+	if peer := p.Next([]*backend.Backend{a, b}, nil); peer != b {
+		t.Error("expected the two samples to be different backends")
+	}
+}
+
+func TestPowerOfTwoChoices_Concurrent(t *testing.T) {
+	p := NewPowerOfTwoChoices()
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", true),
+		createTestBackend("http://localhost:8081", true),
+		createTestBackend("http://localhost:8082", true),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p.Next(backends, nil) == nil {
+				t.Error("expected to get a peer")
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -1,60 +1,183 @@
 package pool
 
 import (
+	"net/http"
 	"net/url"
+	"slices"
+	"sync"
 	"sync/atomic"
 
 	"github.com/eltoncampos/load-balancer/internal/backend"
 )
 
+// ServerPool holds the backend set as an immutable snapshot. Readers load the
+// current slice without locking; writers copy it, modify the copy and publish
+// it, so a slice handed out by GetBackends is never mutated afterwards.
 type ServerPool struct {
-	backends []*backend.Backend
-	current  uint64
+	snapshot atomic.Pointer[snapshot]
+	balancer Balancer
+	locality locality
+	outliers *outlierDetector
+	watchers []func(*backend.Backend) func()
+
+	mux         sync.Mutex
+	unsubscribe map[*backend.Backend]func()
+	updateMux   sync.Mutex
+}
+
+type Option func(*ServerPool)
+
+func WithBalancer(b Balancer) Option {
+	return func(s *ServerPool) {
+		s.balancer = b
+	}
+}
+
+// WithWatcher calls watch for every backend as it joins the pool and the
+// function watch returns once the backend leaves it, so a subscriber such as
+// a health event bus follows pool membership.
+func WithWatcher(watch func(*backend.Backend) func()) Option {
+	return func(s *ServerPool) {
+		s.watchers = append(s.watchers, watch)
+	}
 }
 
-func New() *ServerPool {
-	return &ServerPool{
-		backends: make([]*backend.Backend, 0),
+func New(opts ...Option) *ServerPool {
+	s := &ServerPool{
+		balancer:    NewRoundRobin(),
+		unsubscribe: make(map[*backend.Backend]func()),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.snapshot.Store(newSnapshot(nil, s.locality))
+	return s
 }
 
 func (s *ServerPool) AddBackend(b *backend.Backend) {
-	s.backends = append(s.backends, b)
+	s.mux.Lock()
+	current := s.GetBackends()
+	next := make([]*backend.Backend, len(current), len(current)+1)
+	copy(next, current)
+	s.publish(append(next, b))
+	s.mux.Unlock()
+
+	s.updateBalancer()
+}
+
+func (s *ServerPool) RemoveBackend(backendUrl *url.URL) bool {
+	s.mux.Lock()
+	current := s.GetBackends()
+	next := make([]*backend.Backend, 0, len(current))
+	for _, b := range current {
+		if b.URL.String() != backendUrl.String() {
+			next = append(next, b)
+		}
+	}
+	removed := len(next) != len(current)
+	if removed {
+		s.publish(next)
+	}
+	s.mux.Unlock()
+
+	if removed {
+		s.updateBalancer()
+	}
+	return removed
+}
+
+func (s *ServerPool) ReplaceBackends(backends []*backend.Backend) {
+	s.mux.Lock()
+	s.publish(slices.Clone(backends))
+	s.mux.Unlock()
+
+	s.updateBalancer()
+}
+
+// publish stores the new snapshot and keeps state-change subscriptions in
+// line with it. Callers must hold s.mux.
+func (s *ServerPool) publish(backends []*backend.Backend) {
+	s.snapshot.Store(newSnapshot(backends, s.locality))
+
+	members := make(map[*backend.Backend]bool, len(backends))
+	for _, b := range backends {
+		members[b] = true
+		if _, ok := s.unsubscribe[b]; !ok {
+			s.unsubscribe[b] = s.subscribe(b)
+		}
+	}
+	for b, cancel := range s.unsubscribe {
+		if !members[b] {
+			cancel()
+			delete(s.unsubscribe, b)
+		}
+	}
+}
+
+func (s *ServerPool) subscribe(b *backend.Backend) func() {
+	cancels := []func(){b.OnStateChange(s.backendStateChanged)}
+	if s.outliers != nil {
+		cancels = append(cancels, s.outliers.watch(b))
+	}
+	for _, watch := range s.watchers {
+		cancels = append(cancels, watch(b))
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
 }
 
-func (s *ServerPool) NextIndex() int {
-	return int(atomic.AddUint64(&s.current, uint64(1)) % uint64(len(s.backends)))
+func (s *ServerPool) backendStateChanged(*backend.Backend) {
+	s.updateBalancer()
+}
+
+func (s *ServerPool) updateBalancer() {
+	if u, ok := s.balancer.(Updater); ok {
+		s.updateMux.Lock()
+		defer s.updateMux.Unlock()
+		u.Update(s.snapshot.Load().candidates())
+	}
 }
 
 func (s *ServerPool) GetNextPeer() *backend.Backend {
-	if len(s.backends) == 0 {
+	return s.GetPeer(nil)
+}
+
+func (s *ServerPool) GetPeer(r *http.Request) *backend.Backend {
+	backends := s.snapshot.Load().candidates()
+	if len(backends) == 0 {
 		return nil
 	}
+	return s.balancer.Next(backends, r)
+}
 
-	next := s.NextIndex()
-	l := len(s.backends) + next
+func (s *ServerPool) MarkBackendStatus(backendUrl *url.URL, alive bool) {
+	if b := s.GetBackend(backendUrl); b != nil {
+		b.SetAlive(alive)
+	}
+}
 
-	for i := next; i < l; i++ {
-		idx := i % len(s.backends)
-		if s.backends[idx].IsAlive() {
-			if i != next {
-				atomic.StoreUint64(&s.current, uint64(idx))
-			}
-			return s.backends[idx]
+func (s *ServerPool) GetBackend(backendUrl *url.URL) *backend.Backend {
+	for _, b := range s.GetBackends() {
+		if b.URL.String() == backendUrl.String() {
+			return b
 		}
 	}
 	return nil
 }
 
-func (s *ServerPool) MarkBackendStatus(backendUrl *url.URL, alive bool) {
-	for _, b := range s.backends {
-		if b.URL.String() == backendUrl.String() {
-			b.SetAlive(alive)
-			break
+func (s *ServerPool) GetBackendByID(id string) *backend.Backend {
+	for _, b := range s.GetBackends() {
+		if b.ID() == id {
+			return b
 		}
 	}
+	return nil
 }
 
+// GetBackends returns the current snapshot. It must not be modified.
 func (s *ServerPool) GetBackends() []*backend.Backend {
-	return s.backends
+	return s.snapshot.Load().all
 }
@@ -1,10 +1,14 @@
 package pool
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/http/httputil"
 	"net/url"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/eltoncampos/load-balancer/internal/backend"
 )
@@ -24,16 +28,25 @@ func TestNew(t *testing.T) {
 		t.Error("expected pool to be created")
 	}
 
-	if p.backends == nil {
+	if p.GetBackends() == nil {
 		t.Error("expected backends slice to be initialized")
 	}
 
-	if len(p.backends) != 0 {
-		t.Errorf("expected empty backends, got %d", len(p.backends))
+	if len(p.GetBackends()) != 0 {
+		t.Errorf("expected empty backends, got %d", len(p.GetBackends()))
 	}
 
-	if p.current != 0 {
-		t.Errorf("expected current to be 0, got %d", p.current)
+	if _, ok := p.balancer.(*RoundRobin); !ok {
+		t.Errorf("expected round-robin balancer by default, got %T", p.balancer)
+	}
+}
+
+func TestNew_WithBalancer(t *testing.T) {
+	rr := NewRoundRobin()
+	p := New(WithBalancer(rr))
+
+	if p.balancer != rr {
+		t.Error("expected balancer to match")
 	}
 }
 
@@ -43,11 +56,11 @@ func TestAddBackend(t *testing.T) {
 
 	p.AddBackend(b)
 
-	if len(p.backends) != 1 {
-		t.Errorf("expected 1 backend, got %d", len(p.backends))
+	if len(p.GetBackends()) != 1 {
+		t.Errorf("expected 1 backend, got %d", len(p.GetBackends()))
 	}
 
-	if p.backends[0] != b {
+	if p.GetBackends()[0] != b {
 		t.Error("expected backend to match")
 	}
 }
@@ -62,28 +75,8 @@ func TestAddBackend_Multiple(t *testing.T) {
 	p.AddBackend(b2)
 	p.AddBackend(b3)
 
-	if len(p.backends) != 3 {
-		t.Errorf("expected 3 backends, got %d", len(p.backends))
-	}
-}
-
-func TestNextIndex(t *testing.T) {
-	p := New()
-	p.AddBackend(createTestBackend("http://localhost:8080", true))
-	p.AddBackend(createTestBackend("http://localhost:8081", true))
-	p.AddBackend(createTestBackend("http://localhost:8082", true))
-
-	idx1 := p.NextIndex()
-	idx2 := p.NextIndex()
-	idx3 := p.NextIndex()
-	idx4 := p.NextIndex()
-
-	if idx1 == idx2 && idx2 == idx3 {
-		t.Error("expected indices to rotate")
-	}
-
-	if idx1 != idx4 {
-		t.Error("expected index to wrap around after full rotation")
+	if len(p.GetBackends()) != 3 {
+		t.Errorf("expected 3 backends, got %d", len(p.GetBackends()))
 	}
 }
 
@@ -277,3 +270,231 @@ func TestConcurrentMarkBackendStatus(t *testing.T) {
 		t.Error("expected alive to be either true or false")
 	}
 }
+
+func TestGetPeer_PassesRequestToBalancer(t *testing.T) {
+	key, _ := ParseHashKey("header:X-Key", nil)
+	p := New(WithBalancer(NewRingHash(key, 0)))
+	p.AddBackend(createTestBackend("http://localhost:8080", true))
+	p.AddBackend(createTestBackend("http://localhost:8081", true))
+	p.AddBackend(createTestBackend("http://localhost:8082", true))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Key", "user-1")
+
+	first := p.GetPeer(req)
+	for i := 0; i < 5; i++ {
+		if p.GetPeer(req) != first {
+			t.Fatal("expected the same request key to keep the same backend")
+		}
+	}
+}
+
+func TestConcurrentGetPeerWithHealthFlips(t *testing.T) {
+	for _, strategy := range []string{MaglevStrategy, RingHashStrategy} {
+		t.Run(strategy, func(t *testing.T) {
+			balancer, _ := NewBalancer(strategy, Options{})
+			p := New(WithBalancer(balancer))
+			stable := createTestBackend("http://localhost:8080", true)
+			flapping := createTestBackend("http://localhost:8081", true)
+			p.AddBackend(stable)
+			p.AddBackend(flapping)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 100; i++ {
+				wg.Add(2)
+				go func(i int) {
+					defer wg.Done()
+					req := httptest.NewRequest("GET", fmt.Sprintf("/%d", i), nil)
+					if p.GetPeer(req) == nil {
+						t.Error("expected to get a peer")
+					}
+				}(i)
+				go func(alive bool) {
+					defer wg.Done()
+					flapping.SetAlive(alive)
+				}(i%2 == 0)
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestGetBackendByID(t *testing.T) {
+	p := New()
+	b1 := createTestBackend("http://localhost:8080", true)
+	b2 := createTestBackend("http://localhost:8081", true)
+	p.AddBackend(b1)
+	p.AddBackend(b2)
+
+	if p.GetBackendByID(b2.ID()) != b2 {
+		t.Error("expected to find backend by ID")
+	}
+
+	if p.GetBackendByID("unknown") != nil {
+		t.Error("expected nil for unknown ID")
+	}
+}
+
+func TestRemoveBackend(t *testing.T) {
+	p := New()
+	b1 := createTestBackend("http://localhost:8080", true)
+	b2 := createTestBackend("http://localhost:8081", true)
+	p.AddBackend(b1)
+	p.AddBackend(b2)
+
+	u, _ := url.Parse("http://localhost:8080")
+	if !p.RemoveBackend(u) {
+		t.Fatal("expected backend to be removed")
+	}
+
+	backends := p.GetBackends()
+	if len(backends) != 1 || backends[0] != b2 {
+		t.Errorf("expected only second backend to remain, got %d backends", len(backends))
+	}
+
+	if p.RemoveBackend(u) {
+		t.Error("expected removing an unknown backend to report false")
+	}
+}
+
+func TestRemoveBackend_NotSelectedAnymore(t *testing.T) {
+	p := New()
+	b1 := createTestBackend("http://localhost:8080", true)
+	b2 := createTestBackend("http://localhost:8081", true)
+	p.AddBackend(b1)
+	p.AddBackend(b2)
+
+	p.RemoveBackend(b1.URL)
+
+	for i := 0; i < 4; i++ {
+		if p.GetNextPeer() != b2 {
+			t.Fatal("expected removed backend never to be selected")
+		}
+	}
+}
+
+func TestReplaceBackends(t *testing.T) {
+	p := New()
+	old := createTestBackend("http://localhost:8080", true)
+	p.AddBackend(old)
+
+	b1 := createTestBackend("http://localhost:9090", true)
+	b2 := createTestBackend("http://localhost:9091", true)
+	input := []*backend.Backend{b1, b2}
+	p.ReplaceBackends(input)
+
+	input[0] = old
+	backends := p.GetBackends()
+	if len(backends) != 2 || backends[0] != b1 || backends[1] != b2 {
+		t.Error("expected pool to hold a copy of the new backend set")
+	}
+}
+
+func TestGetBackends_SnapshotIsStable(t *testing.T) {
+	p := New()
+	b1 := createTestBackend("http://localhost:8080", true)
+	p.AddBackend(b1)
+
+	snapshot := p.GetBackends()
+	p.AddBackend(createTestBackend("http://localhost:8081", true))
+	p.RemoveBackend(b1.URL)
+
+	if len(snapshot) != 1 || snapshot[0] != b1 {
+		t.Error("expected earlier snapshot to be unaffected by later changes")
+	}
+}
+
+func TestRemoveBackend_StopsStateChangeUpdates(t *testing.T) {
+	m := NewMaglev(nil, 13)
+	p := New(WithBalancer(m))
+	b1 := createTestBackend("http://localhost:8080", true)
+	b2 := createTestBackend("http://localhost:8081", true)
+	p.AddBackend(b1)
+	p.AddBackend(b2)
+	p.RemoveBackend(b1.URL)
+
+	table := m.table.Load()
+	b1.SetAlive(false)
+
+	if m.table.Load() != table {
+		t.Error("expected removed backend not to trigger balancer updates")
+	}
+}
+
+func TestWithWatcher_FollowsMembership(t *testing.T) {
+	watched := make(map[*backend.Backend]bool)
+	p := New(WithWatcher(func(b *backend.Backend) func() {
+		watched[b] = true
+		return func() { delete(watched, b) }
+	}))
+	b1 := createTestBackend("http://localhost:8080", true)
+	b2 := createTestBackend("http://localhost:8081", true)
+	p.AddBackend(b1)
+	p.AddBackend(b2)
+
+	if !watched[b1] || !watched[b2] {
+		t.Fatal("expected added backends to be watched")
+	}
+
+	p.RemoveBackend(b1.URL)
+
+	if watched[b1] || !watched[b2] {
+		t.Error("expected only the removed backend to stop being watched")
+	}
+}
+
+func TestConcurrentMembershipChanges(t *testing.T) {
+	for _, strategy := range Strategies() {
+		t.Run(strategy, func(t *testing.T) {
+			balancer, _ := NewBalancer(strategy, Options{})
+			p := New(WithBalancer(balancer))
+			stable := createTestBackend("http://localhost:8080", true)
+			p.AddBackend(stable)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(4)
+				go func(i int) {
+					defer wg.Done()
+					p.AddBackend(createTestBackend(fmt.Sprintf("http://localhost:%d", 9000+i), true))
+				}(i)
+				go func(i int) {
+					defer wg.Done()
+					u, _ := url.Parse(fmt.Sprintf("http://localhost:%d", 9000+i))
+					p.RemoveBackend(u)
+				}(i)
+				go func(i int) {
+					defer wg.Done()
+					req := httptest.NewRequest("GET", fmt.Sprintf("/%d", i), nil)
+					if p.GetPeer(req) == nil {
+						t.Error("expected to get a peer")
+					}
+				}(i)
+				go func() {
+					defer wg.Done()
+					p.ReplaceBackends([]*backend.Backend{stable})
+				}()
+			}
+
+			wg.Wait()
+		})
+	}
+}
+
+func TestGetNextPeer_SkipsOpenCircuits(t *testing.T) {
+	p := New()
+	tripped, _ := createStubBackend("http://10.0.0.1:8080", http.StatusInternalServerError)
+	tripped.Breaker = backend.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour}
+	healthy, _ := createStubBackend("http://10.0.0.2:8080", http.StatusOK)
+	p.AddBackend(tripped)
+	p.AddBackend(healthy)
+
+	serve(tripped, 2)
+
+	for i := 0; i < 10; i++ {
+		if p.GetNextPeer() != healthy {
+			t.Fatal("expected backend with an open circuit to be skipped")
+		}
+	}
+}
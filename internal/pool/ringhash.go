@@ -0,0 +1,178 @@
+package pool
+
+import (
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const (
+	RingHashStrategy = "ring-hash"
+	DefaultReplicas  = 100
+)
+
+func init() {
+	Register(RingHashStrategy, func(opts Options) Balancer {
+		return NewRingHash(opts.HashKey, opts.Replicas)
+	})
+}
+
+// RingHash places every backend on a hash ring as Replicas*Weight virtual
+// nodes and routes a request to the first node clockwise from its key, so
+// membership changes only remap the keys owned by the affected backend.
+// During slow start only a share of a backend's nodes, growing with its
+// effective weight, take keys.
+type RingHash struct {
+	key      KeyFunc
+	replicas int
+
+	mux   sync.RWMutex
+	rings map[memberKey][]ringNode
+}
+
+type ringNode struct {
+	hash    uint64
+	backend *backend.Backend
+	// rank places the node among its backend's nodes, from 0 up to 1.
+	rank float64
+}
+
+// serving reports whether the node takes keys: its backend is available and,
+// while ramping up, the node falls within the share it has reached.
+func (n ringNode) serving() bool {
+	return n.backend.IsAvailable() && n.rank < rampShare(n.backend)
+}
+
+// rampShare is the fraction of its full weight a backend currently receives;
+// it is below 1 only during slow start.
+func rampShare(b *backend.Backend) float64 {
+	if b.Weight <= 0 {
+		return 1
+	}
+	return b.EffectiveWeight() / float64(b.Weight)
+}
+
+func NewRingHash(key KeyFunc, replicas int) *RingHash {
+	if key == nil {
+		key, _ = ParseHashKey("path", nil)
+	}
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	return &RingHash{
+		key:      key,
+		replicas: replicas,
+	}
+}
+
+func (h *RingHash) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	ring := h.ringFor(backends)
+	if len(ring) == 0 {
+		return nil
+	}
+
+	hash := hashKey(requestKey(h.key, r))
+	start := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= hash })
+
+	for i := 0; i < len(ring); i++ {
+		if n := ring[(start+i)%len(ring)]; n.serving() {
+			return n.backend
+		}
+	}
+	return nil
+}
+
+func (h *RingHash) ringFor(backends []*backend.Backend) []ringNode {
+	key := membersKey(backends)
+	h.mux.RLock()
+	ring, ok := h.rings[key]
+	h.mux.RUnlock()
+	if ok {
+		return ring
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if ring, ok := h.rings[key]; ok {
+		return ring
+	}
+	if h.rings == nil || len(h.rings) >= maxCachedMembers {
+		h.rings = make(map[memberKey][]ringNode)
+	}
+	ring = buildRing(backends, h.replicas)
+	h.rings[key] = ring
+	return ring
+}
+
+func buildRing(backends []*backend.Backend, replicas int) []ringNode {
+	ring := make([]ringNode, 0, len(backends)*replicas)
+	for _, b := range backends {
+		id := b.URL.String()
+		nodes := replicas * max(b.Weight, 1)
+		for i := 0; i < nodes; i++ {
+			ring = append(ring, ringNode{
+				hash:    hashKey(id + "#" + strconv.Itoa(i)),
+				backend: b,
+				rank:    float64(i) / float64(nodes),
+			})
+		}
+	}
+	slices.SortFunc(ring, func(a, b ringNode) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		}
+		return 0
+	})
+	return ring
+}
+
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix64(h.Sum64())
+}
+
+// mix64 is the splitmix64 finalizer; FNV alone clusters similar keys such as
+// "host#1" and "host#2" too tightly for an even ring.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// maxCachedMembers bounds how many backend lists a hashing balancer keeps
+// state for. With zone spillover a tier's local, remote and full lists are
+// used side by side; lists from older pool snapshots are dropped once the
+// cache fills up.
+const maxCachedMembers = 8
+
+// memberKey identifies a backend list the same way sameMembers compares them.
+type memberKey struct {
+	first **backend.Backend
+	n     int
+}
+
+func membersKey(backends []*backend.Backend) memberKey {
+	if len(backends) == 0 {
+		return memberKey{}
+	}
+	return memberKey{first: &backends[0], n: len(backends)}
+}
+
+// sameMembers reports whether two backend lists are the same slice. The pool
+// never mutates a published backend list in place, so this is a cheap way to
+// notice membership changes.
+func sameMembers(a, b []*backend.Backend) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
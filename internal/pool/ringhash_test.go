@@ -0,0 +1,223 @@
+package pool
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func createHashBackends(n int) []*backend.Backend {
+	backends := make([]*backend.Backend, n)
+	for i := range backends {
+		backends[i] = createTestBackend(fmt.Sprintf("http://10.0.0.%d:8080", i+1), true)
+	}
+	return backends
+}
+
+func requestForPath(path string) *http.Request {
+	return httptest.NewRequest("GET", path, nil)
+}
+
+func mapKeys(b Balancer, backends []*backend.Backend, keys int) map[string]*backend.Backend {
+	assigned := make(map[string]*backend.Backend, keys)
+	for i := 0; i < keys; i++ {
+		path := fmt.Sprintf("/object/%d", i)
+		assigned[path] = b.Next(backends, requestForPath(path))
+	}
+	return assigned
+}
+
+func TestRingHash_SameKeySameBackend(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(5)
+
+	first := h.Next(backends, requestForPath("/cache/item"))
+	for i := 0; i < 10; i++ {
+		if peer := h.Next(backends, requestForPath("/cache/item")); peer != first {
+			t.Fatal("expected the same key to always map to the same backend")
+		}
+	}
+}
+
+func TestRingHash_Distribution(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(4)
+
+	counts := make(map[*backend.Backend]int)
+	for _, b := range mapKeys(h, backends, 10000) {
+		counts[b]++
+	}
+
+	for i, b := range backends {
+		if counts[b] < 1500 || counts[b] > 3500 {
+			t.Errorf("expected backend %d to get roughly a quarter of keys, got %d", i, counts[b])
+		}
+	}
+}
+
+func TestRingHash_RemovingBackendOnlyRemapsItsKeys(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(5)
+	before := mapKeys(h, backends, 2000)
+
+	removed := backends[2]
+	remaining := append(append([]*backend.Backend{}, backends[:2]...), backends[3:]...)
+	after := mapKeys(h, remaining, 2000)
+
+	for key, b := range before {
+		if b != removed && after[key] != b {
+			t.Fatalf("key %s moved from a backend that was not removed", key)
+		}
+		if after[key] == removed {
+			t.Fatalf("key %s still mapped to removed backend", key)
+		}
+	}
+}
+
+func TestRingHash_AddingBackendRemapsAboutOneNth(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(5)
+	before := mapKeys(h, backends[:4], 10000)
+	after := mapKeys(h, backends, 10000)
+
+	moved := 0
+	for key, b := range before {
+		if after[key] != b {
+			if after[key] != backends[4] {
+				t.Fatalf("key %s moved to a backend other than the new one", key)
+			}
+			moved++
+		}
+	}
+
+	if moved < 1000 || moved > 3000 {
+		t.Errorf("expected about 1/5 of keys to move, got %d of 10000", moved)
+	}
+}
+
+func TestRingHash_SkipsDeadBackends(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(4)
+	before := mapKeys(h, backends, 1000)
+
+	dead := backends[1]
+	dead.SetAlive(false)
+	after := mapKeys(h, backends, 1000)
+
+	for key, b := range before {
+		if after[key] == dead {
+			t.Fatalf("key %s routed to dead backend", key)
+		}
+		if b != dead && after[key] != b {
+			t.Fatalf("key %s moved although its backend is alive", key)
+		}
+	}
+
+	dead.SetAlive(true)
+	restored := mapKeys(h, backends, 1000)
+	for key, b := range before {
+		if restored[key] != b {
+			t.Fatalf("key %s did not return to its backend after recovery", key)
+		}
+	}
+}
+
+func TestRingHash_AllDead(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(2)
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+
+	if peer := h.Next(backends, requestForPath("/")); peer != nil {
+		t.Error("expected nil when all backends are dead")
+	}
+
+	if peer := h.Next(nil, requestForPath("/")); peer != nil {
+		t.Error("expected nil for empty backend list")
+	}
+}
+
+func TestRingHash_HeaderKey(t *testing.T) {
+	key, _ := ParseHashKey("header:X-Session", nil)
+	h := NewRingHash(key, 50)
+	backends := createHashBackends(3)
+
+	req1 := requestForPath("/a")
+	req1.Header.Set("X-Session", "abc")
+	req2 := requestForPath("/b")
+	req2.Header.Set("X-Session", "abc")
+
+	if h.Next(backends, req1) != h.Next(backends, req2) {
+		t.Error("expected requests with the same header to share a backend")
+	}
+}
+
+func TestRingHash_WeightAddsVirtualNodes(t *testing.T) {
+	backends := createHashBackends(2)
+	backends[0].Weight = 3
+
+	ring := buildRing(backends, 10)
+
+	counts := make(map[*backend.Backend]int)
+	for _, node := range ring {
+		counts[node.backend]++
+	}
+
+	if counts[backends[0]] != 30 || counts[backends[1]] != 10 {
+		t.Errorf("expected 30 and 10 virtual nodes, got %d and %d", counts[backends[0]], counts[backends[1]])
+	}
+}
+
+func TestRingHash_Concurrent(t *testing.T) {
+	h := NewRingHash(nil, 0)
+	backends := createHashBackends(3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if h.Next(backends, requestForPath(fmt.Sprintf("/%d", i))) == nil {
+				t.Error("expected to get a peer")
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestRingHash_SlowStart(t *testing.T) {
+	backends := createHashBackends(2)
+	recovering := backends[1]
+	recovering.SlowStart = time.Hour
+	recovering.SetAlive(false)
+	recovering.SetAlive(true)
+
+	counts := make(map[*backend.Backend]int)
+	for _, b := range mapKeys(NewRingHash(nil, 0), backends, 2000) {
+		counts[b]++
+	}
+
+	if counts[recovering] == 0 || counts[recovering] > 400 {
+		t.Errorf("expected recovering backend to get a small share of keys, got %d of 2000", counts[recovering])
+	}
+}
+
+func TestRingHash_CachesRingPerBackendList(t *testing.T) {
+	h := NewRingHash(nil, 10)
+	backends := createHashBackends(4)
+	local, remote := backends[:2:2], backends[2:]
+
+	first := h.ringFor(local)
+	h.ringFor(remote)
+
+	if &h.ringFor(local)[0] != &first[0] {
+		t.Error("expected alternating backend lists not to rebuild the ring")
+	}
+}
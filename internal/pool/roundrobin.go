@@ -0,0 +1,46 @@
+package pool
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const RoundRobinStrategy = "round-robin"
+
+func init() {
+	Register(RoundRobinStrategy, func(Options) Balancer { return NewRoundRobin() })
+}
+
+type RoundRobin struct {
+	current uint64
+}
+
+func NewRoundRobin() *RoundRobin {
+	return &RoundRobin{}
+}
+
+func (rr *RoundRobin) NextIndex(n int) int {
+	return int(atomic.AddUint64(&rr.current, uint64(1)) % uint64(n))
+}
+
+func (rr *RoundRobin) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+
+	next := rr.NextIndex(len(backends))
+	l := len(backends) + next
+
+	for i := next; i < l; i++ {
+		idx := i % len(backends)
+		if backends[idx].IsAvailable() {
+			if i != next {
+				atomic.StoreUint64(&rr.current, uint64(idx))
+			}
+			return backends[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func TestRoundRobin_NextIndex(t *testing.T) {
+	rr := NewRoundRobin()
+
+	idx1 := rr.NextIndex(3)
+	idx2 := rr.NextIndex(3)
+	idx3 := rr.NextIndex(3)
+	idx4 := rr.NextIndex(3)
+
+	if idx1 == idx2 && idx2 == idx3 {
+		t.Error("expected indices to rotate")
+	}
+
+	if idx1 != idx4 {
+		t.Error("expected index to wrap around after full rotation")
+	}
+}
+
+func TestRoundRobin_Next(t *testing.T) {
+	rr := NewRoundRobin()
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", true),
+		createTestBackend("http://localhost:8081", true),
+		createTestBackend("http://localhost:8082", true),
+	}
+
+	seen := make(map[*backend.Backend]int)
+	for i := 0; i < 6; i++ {
+		seen[rr.Next(backends, nil)]++
+	}
+
+	for i, b := range backends {
+		if seen[b] != 2 {
+			t.Errorf("expected backend %d to be picked twice, got %d", i, seen[b])
+		}
+	}
+}
+
+func TestRoundRobin_NextSkipsDeadBackends(t *testing.T) {
+	rr := NewRoundRobin()
+	alive := createTestBackend("http://localhost:8081", true)
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", false),
+		alive,
+	}
+
+	for i := 0; i < 4; i++ {
+		if peer := rr.Next(backends, nil); peer != alive {
+			t.Fatal("expected only the alive backend to be picked")
+		}
+	}
+}
+
+func TestRoundRobin_NextEmpty(t *testing.T) {
+	rr := NewRoundRobin()
+
+	if peer := rr.Next(nil, nil); peer != nil {
+		t.Error("expected nil for empty backend list")
+	}
+}
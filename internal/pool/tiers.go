@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"slices"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+// snapshot is an immutable view of the pool. Backends are also grouped into
+// priority tiers, lowest Priority first; each tier slice stays the same for
+// the snapshot's lifetime so balancers can cache state keyed on it.
+type snapshot struct {
+	all      []*backend.Backend
+	tiers    [][]*backend.Backend
+	local    [][]*backend.Backend
+	remote   [][]*backend.Backend
+	locality locality
+}
+
+func newSnapshot(backends []*backend.Backend, loc locality) *snapshot {
+	if backends == nil {
+		backends = []*backend.Backend{}
+	}
+	s := &snapshot{
+		all:      backends,
+		tiers:    splitTiers(backends),
+		locality: loc,
+	}
+	s.splitZones()
+	return s
+}
+
+func splitTiers(backends []*backend.Backend) [][]*backend.Backend {
+	priorities := make([]int, 0)
+	for _, b := range backends {
+		if !slices.Contains(priorities, b.Priority) {
+			priorities = append(priorities, b.Priority)
+		}
+	}
+	slices.Sort(priorities)
+
+	if len(priorities) <= 1 {
+		return [][]*backend.Backend{backends}
+	}
+
+	tiers := make([][]*backend.Backend, 0, len(priorities))
+	for _, p := range priorities {
+		tier := make([]*backend.Backend, 0)
+		for _, b := range backends {
+			if b.Priority == p {
+				tier = append(tier, b)
+			}
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+// candidates returns the backends the balancer should choose from right now.
+func (s *snapshot) candidates() []*backend.Backend {
+	i := s.activeTier()
+	if s.local != nil {
+		return s.locality.pick(s.local[i], s.remote[i], s.tiers[i])
+	}
+	return s.tiers[i]
+}
+
+// activeTier is the index of the highest priority tier with at least one
+// backend that can take traffic. Lower tiers only see requests once every
+// backend above them is dead, draining or ejected.
+func (s *snapshot) activeTier() int {
+	for i, tier := range s.tiers {
+		for _, b := range tier {
+			if b.IsAvailable() {
+				return i
+			}
+		}
+	}
+	return 0
+}
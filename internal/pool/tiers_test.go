@@ -0,0 +1,157 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func createTieredBackend(urlStr string, priority int) *backend.Backend {
+	b := createTestBackend(urlStr, true)
+	b.Priority = priority
+	return b
+}
+
+func TestNewSnapshot_GroupsByPriority(t *testing.T) {
+	p0 := createTieredBackend("http://localhost:8080", 0)
+	p2 := createTieredBackend("http://localhost:8081", 2)
+	p1 := createTieredBackend("http://localhost:8082", 1)
+	p0b := createTieredBackend("http://localhost:8083", 0)
+
+	s := newSnapshot([]*backend.Backend{p0, p2, p1, p0b}, locality{})
+
+	if len(s.tiers) != 3 {
+		t.Fatalf("expected 3 tiers, got %d", len(s.tiers))
+	}
+
+	if len(s.tiers[0]) != 2 || s.tiers[0][0] != p0 || s.tiers[0][1] != p0b {
+		t.Error("expected first tier to hold priority 0 backends in order")
+	}
+
+	if s.tiers[1][0] != p1 || s.tiers[2][0] != p2 {
+		t.Error("expected tiers to be sorted by priority")
+	}
+}
+
+func TestNewSnapshot_SingleTierReusesSlice(t *testing.T) {
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", true),
+		createTestBackend("http://localhost:8081", true),
+	}
+
+	s := newSnapshot(backends, locality{})
+
+	if !sameMembers(s.tiers[0], backends) {
+		t.Error("expected a single tier to share the full backend slice")
+	}
+}
+
+func TestGetPeer_PrefersPrimaryTier(t *testing.T) {
+	p := New()
+	primary1 := createTieredBackend("http://localhost:8080", 0)
+	primary2 := createTieredBackend("http://localhost:8081", 0)
+	backup := createTieredBackend("http://localhost:9090", 1)
+	p.AddBackend(backup)
+	p.AddBackend(primary1)
+	p.AddBackend(primary2)
+
+	for i := 0; i < 10; i++ {
+		if p.GetNextPeer() == backup {
+			t.Fatal("expected backup not to receive traffic while primaries are up")
+		}
+	}
+
+	primary1.SetAlive(false)
+	for i := 0; i < 4; i++ {
+		if p.GetNextPeer() != primary2 {
+			t.Fatal("expected remaining primary to take all traffic")
+		}
+	}
+}
+
+func TestGetPeer_FailsOverToBackupTier(t *testing.T) {
+	p := New()
+	primary := createTieredBackend("http://localhost:8080", 0)
+	backup := createTieredBackend("http://localhost:9090", 1)
+	p.AddBackend(primary)
+	p.AddBackend(backup)
+
+	primary.SetAlive(false)
+	if p.GetNextPeer() != backup {
+		t.Error("expected backup to take traffic once primaries are dead")
+	}
+
+	primary.SetAlive(true)
+	if p.GetNextPeer() != primary {
+		t.Error("expected traffic to return to the primary tier")
+	}
+}
+
+func TestGetPeer_FailsOverWhenPrimaryDraining(t *testing.T) {
+	p := New()
+	primary := createTieredBackend("http://localhost:8080", 0)
+	backup := createTieredBackend("http://localhost:9090", 1)
+	p.AddBackend(primary)
+	p.AddBackend(backup)
+	primary.IncActive()
+
+	p.Drain(primary.URL, time.Minute)
+
+	if p.GetNextPeer() != backup {
+		t.Error("expected backup to take traffic while the primary drains")
+	}
+}
+
+func TestGetPeer_SkipsEmptyTiers(t *testing.T) {
+	p := New()
+	p.AddBackend(createTieredBackend("http://localhost:8080", 0))
+	p.AddBackend(createTieredBackend("http://localhost:8081", 1))
+	last := createTieredBackend("http://localhost:8082", 2)
+	p.AddBackend(last)
+
+	for _, b := range p.GetBackends()[:2] {
+		b.SetAlive(false)
+	}
+
+	if p.GetNextPeer() != last {
+		t.Error("expected the lowest tier to be used when all others are down")
+	}
+}
+
+func TestGetPeer_AllTiersDown(t *testing.T) {
+	p := New()
+	p.AddBackend(createTieredBackend("http://localhost:8080", 0))
+	p.AddBackend(createTieredBackend("http://localhost:8081", 1))
+
+	for _, b := range p.GetBackends() {
+		b.SetAlive(false)
+	}
+
+	if p.GetNextPeer() != nil {
+		t.Error("expected nil when every tier is down")
+	}
+}
+
+func TestGetPeer_MaglevUsesActiveTier(t *testing.T) {
+	m := NewMaglev(nil, 13)
+	p := New(WithBalancer(m))
+	primary := createTieredBackend("http://localhost:8080", 0)
+	backup := createTieredBackend("http://localhost:9090", 1)
+	p.AddBackend(primary)
+	p.AddBackend(backup)
+
+	if table := m.table.Load(); len(table.alive) != 1 || table.alive[0] != primary {
+		t.Fatal("expected table to be built from the primary tier only")
+	}
+
+	primary.SetAlive(false)
+
+	if table := m.table.Load(); len(table.alive) != 1 || table.alive[0] != backup {
+		t.Fatal("expected table to be rebuilt from the backup tier")
+	}
+
+	if p.GetPeer(requestForPath("/")) != backup {
+		t.Error("expected backup to be selected")
+	}
+}
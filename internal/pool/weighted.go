@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+const WeightedRoundRobinStrategy = "weighted-round-robin"
+
+func init() {
+	Register(WeightedRoundRobinStrategy, func(Options) Balancer { return NewWeightedRoundRobin() })
+}
+
+// WeightedRoundRobin implements nginx's smooth weighted round robin: every
+// pick adds each backend's weight to its current weight, selects the highest
+// and subtracts the total from the winner, which interleaves heavy backends
+// with light ones instead of sending them bursts. Backends in slow start
+// take part with their reduced effective weight. Current weights are kept per
+// backend list, so zone spillover alternating between lists does not reset
+// the rotation of either.
+type WeightedRoundRobin struct {
+	mux     sync.Mutex
+	current map[memberKey]map[*backend.Backend]float64
+}
+
+func NewWeightedRoundRobin() *WeightedRoundRobin {
+	return &WeightedRoundRobin{
+		current: make(map[memberKey]map[*backend.Backend]float64),
+	}
+}
+
+func (w *WeightedRoundRobin) Next(backends []*backend.Backend, r *http.Request) *backend.Backend {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	current := w.stateFor(backends)
+	var best *backend.Backend
+	total := 0.0
+
+	for _, b := range backends {
+		weight := b.EffectiveWeight()
+		if !b.IsAvailable() || weight <= 0 {
+			continue
+		}
+		current[b] += weight
+		total += weight
+		if best == nil || current[b] > current[best] {
+			best = b
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	current[best] -= total
+	return best
+}
+
+// stateFor returns the current weights of a backend list. State of lists from
+// older pool snapshots is dropped once the cache fills up. Callers must hold
+// w.mux.
+func (w *WeightedRoundRobin) stateFor(backends []*backend.Backend) map[*backend.Backend]float64 {
+	key := membersKey(backends)
+	if current, ok := w.current[key]; ok {
+		return current
+	}
+	if len(w.current) >= maxCachedMembers {
+		w.current = make(map[memberKey]map[*backend.Backend]float64)
+	}
+	current := make(map[*backend.Backend]float64, len(backends))
+	w.current[key] = current
+	return current
+}
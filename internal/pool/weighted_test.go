@@ -0,0 +1,133 @@
+package pool
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eltoncampos/load-balancer/internal/backend"
+)
+
+func createWeightedBackend(urlStr string, weight int) *backend.Backend {
+	b := createTestBackend(urlStr, true)
+	b.Weight = weight
+	return b
+}
+
+func TestWeightedRoundRobin_Proportional(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	a := createWeightedBackend("http://localhost:8080", 5)
+	b := createWeightedBackend("http://localhost:8081", 1)
+	c := createWeightedBackend("http://localhost:8082", 1)
+	backends := []*backend.Backend{a, b, c}
+
+	counts := make(map[*backend.Backend]int)
+	for i := 0; i < 70; i++ {
+		counts[w.Next(backends, nil)]++
+	}
+
+	if counts[a] != 50 || counts[b] != 10 || counts[c] != 10 {
+		t.Errorf("expected 50/10/10 distribution, got %d/%d/%d", counts[a], counts[b], counts[c])
+	}
+}
+
+func TestWeightedRoundRobin_Smooth(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	a := createWeightedBackend("http://a:80", 5)
+	b := createWeightedBackend("http://b:80", 1)
+	c := createWeightedBackend("http://c:80", 1)
+	backends := []*backend.Backend{a, b, c}
+
+	var seq strings.Builder
+	for i := 0; i < 7; i++ {
+		seq.WriteString(w.Next(backends, nil).URL.Hostname())
+	}
+
+	// Same sequence nginx produces for weights {5, 1, 1}.
+	if seq.String() != "aabacaa" {
+		t.Errorf("expected smooth sequence aabacaa, got %s", seq.String())
+	}
+}
+
+func TestWeightedRoundRobin_SkipsDeadBackends(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	dead := createWeightedBackend("http://localhost:8080", 10)
+	dead.SetAlive(false)
+	alive := createWeightedBackend("http://localhost:8081", 1)
+	backends := []*backend.Backend{dead, alive}
+
+	for i := 0; i < 5; i++ {
+		if peer := w.Next(backends, nil); peer != alive {
+			t.Fatal("expected only the alive backend to be picked")
+		}
+	}
+}
+
+func TestWeightedRoundRobin_AllDead(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	backends := []*backend.Backend{
+		createTestBackend("http://localhost:8080", false),
+		createTestBackend("http://localhost:8081", false),
+	}
+
+	if peer := w.Next(backends, nil); peer != nil {
+		t.Error("expected nil when all backends are dead")
+	}
+}
+
+func TestWeightedRoundRobin_DropsStateOfOldLists(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	a := createWeightedBackend("http://localhost:8080", 1)
+	b := createWeightedBackend("http://localhost:8081", 1)
+
+	for i := 0; i < 2*maxCachedMembers; i++ {
+		w.Next([]*backend.Backend{a, b}, nil)
+	}
+
+	if len(w.current) > maxCachedMembers {
+		t.Errorf("expected at most %d cached lists, got %d", maxCachedMembers, len(w.current))
+	}
+}
+
+func TestWeightedRoundRobin_AlternatingListsKeepRotation(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	a := createWeightedBackend("http://a:80", 1)
+	b := createWeightedBackend("http://b:80", 1)
+	c := createWeightedBackend("http://c:80", 1)
+	d := createWeightedBackend("http://d:80", 1)
+	e := createWeightedBackend("http://e:80", 1)
+	first := []*backend.Backend{a, b}
+	second := []*backend.Backend{c, d, e}
+
+	counts := make(map[*backend.Backend]int)
+	for i := 0; i < 60; i++ {
+		counts[w.Next(first, nil)]++
+		counts[w.Next(second, nil)]++
+	}
+
+	if counts[a] != 30 || counts[b] != 30 {
+		t.Errorf("expected 30/30 across the first list, got %d/%d", counts[a], counts[b])
+	}
+	if counts[c] != 20 || counts[d] != 20 || counts[e] != 20 {
+		t.Errorf("expected 20/20/20 across the second list, got %d/%d/%d", counts[c], counts[d], counts[e])
+	}
+}
+
+func TestWeightedRoundRobin_SlowStart(t *testing.T) {
+	w := NewWeightedRoundRobin()
+	steady := createWeightedBackend("http://localhost:8080", 1)
+	recovering := createWeightedBackend("http://localhost:8081", 1)
+	recovering.SlowStart = time.Hour
+	recovering.SetAlive(false)
+	recovering.SetAlive(true)
+	backends := []*backend.Backend{steady, recovering}
+
+	counts := make(map[*backend.Backend]int)
+	for i := 0; i < 110; i++ {
+		counts[w.Next(backends, nil)]++
+	}
+
+	if counts[recovering] < 8 || counts[recovering] > 12 {
+		t.Errorf("expected recovering backend to get about 10%% of traffic, got %d of 110", counts[recovering])
+	}
+}